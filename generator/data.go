@@ -4,20 +4,41 @@ import (
 	"encoding/base64"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
+	"strings"
 	"time"
 
+	"github.com/go-openapi/jsonpointer"
 	"github.com/go-openapi/spec"
 )
 
+// SchemaError is the value that StubSchema panics with
+// when it's given a schema that it can't stub
+type SchemaError struct {
+	// Path is the location of the failing schema relative
+	// to the schema given to StubSchema i.e. #/properties/pets/items
+	Path    string
+	Message string
+}
+
+func (err *SchemaError) Error() string {
+	return fmt.Sprintf("%v at schema path %v", err.Message, err.Path)
+}
+
 // StubSchema returns a struct that matches the openapi schema
-// with values filled with randomly generated data
+// with values filled with randomly generated data.
+// It panics with a *SchemaError if the schema can't be stubbed.
 func StubSchema(schema spec.Schema) interface{} {
+	return stubSchema(schema, "#")
+}
+
+func stubSchema(schema spec.Schema, path string) interface{} {
 	if schema.Type.Contains("object") {
-		return objectStub(schema)
+		return objectStub(schema, path)
 
 	} else if schema.Type.Contains("array") {
-		return arrayStub(schema)
+		return arrayStub(schema, path)
 
 	} else if schema.Type.Contains("string") {
 		return stringStub(schema)
@@ -36,22 +57,125 @@ func StubSchema(schema spec.Schema) interface{} {
 		// but there were `properties`.
 		// we'll log a warning and then hope it's actually an object
 		log.Printf("unknown schema type \"%v\". assuming type object", schema.Type)
-		return objectStub(schema)
+		return objectStub(schema, path)
+	}
+
+	panic(&SchemaError{
+		Path:    path,
+		Message: fmt.Sprintf("unknown schema type \"%v\" for schema \"%v\"", schema.Type, schema.ID),
+	})
+}
+
+// MinimalStub returns the smallest value that matches the openapi
+// schema. Unlike StubSchema it's always the same: objects only have
+// their required properties, arrays have their minimum number of items
+// and other values are the first enum value, the default or the minimum.
+// It panics with a *SchemaError if the schema can't be stubbed.
+func MinimalStub(schema spec.Schema) interface{} {
+	return minimalStub(schema, "#")
+}
+
+func minimalStub(schema spec.Schema, path string) interface{} {
+	if len(schema.Enum) != 0 {
+		return schema.Enum[0]
+	}
+	if schema.Default != nil {
+		return schema.Default
+	}
+
+	if schema.Type.Contains("object") || (len(schema.Type) == 0 && len(schema.Properties) != 0) {
+		obj := map[string]interface{}{}
+		for _, property := range schema.Required {
+			// a required property without a schema can be anything
+			obj[property] = nil
+			if propSchema, ok := schema.Properties[property]; ok {
+				obj[property] = minimalStub(propSchema, path+"/properties/"+jsonpointer.Escape(property))
+			}
+		}
+		return obj
+
+	} else if schema.Type.Contains("array") {
+		items := []interface{}{}
+		if schema.MinItems != nil && schema.Items != nil && schema.Items.Schema != nil {
+			for i := int64(0); i < *schema.MinItems; i++ {
+				items = append(items, minimalStub(*schema.Items.Schema, path+"/items"))
+			}
+		}
+		return items
+
+	} else if schema.Type.Contains("string") {
+		return minimalString(schema)
+
+	} else if schema.Type.Contains("number") {
+		return minimalNumber(schema, 0.5)
+
+	} else if schema.Type.Contains("integer") {
+		return minimalNumber(schema, 1)
+
+	} else if schema.Type.Contains("boolean") {
+		return false
+	}
+
+	panic(&SchemaError{
+		Path:    path,
+		Message: fmt.Sprintf("unknown schema type \"%v\" for schema \"%v\"", schema.Type, schema.ID),
+	})
+}
+
+// minimalFormats are the smallest valid values
+// of the string formats that are validated
+var minimalFormats = map[string]string{
+	"date":      "1970-01-01",
+	"date-time": "1970-01-01T00:00:00Z",
+	"email":     "a@example.com",
+	"hostname":  "example.com",
+	"ipv4":      "127.0.0.1",
+	"ipv6":      "::1",
+	"uri":       "http://example.com",
+	"uuid":      "00000000-0000-0000-0000-000000000000",
+}
+
+func minimalString(schema spec.Schema) string {
+	if value, ok := minimalFormats[schema.Format]; ok {
+		return value
 	}
+	if schema.MinLength != nil {
+		return strings.Repeat("a", int(*schema.MinLength))
+	}
+	return ""
+}
 
-	panic(fmt.Sprintf("unknown schema type \"%v\" for schema \"%v\"", schema.Type, schema.ID))
+// minimalNumber returns zero or the closest value to it within
+// the schema's bounds. step is how far an exclusive bound is moved.
+func minimalNumber(schema spec.Schema, step float64) float64 {
+	value := 0.0
+	if schema.Minimum != nil && *schema.Minimum >= value {
+		value = *schema.Minimum
+		if schema.ExclusiveMinimum {
+			value += step
+		}
+	} else if schema.Maximum != nil && *schema.Maximum <= value {
+		value = *schema.Maximum
+		if schema.ExclusiveMaximum {
+			value -= step
+		}
+	}
+	if step == 1 {
+		value = math.Ceil(value)
+	}
+	return value
 }
 
-func objectStub(schema spec.Schema) interface{} {
+func objectStub(schema spec.Schema, path string) interface{} {
 	obj := map[string]interface{}{}
 	for property, propSchema := range schema.Properties {
-		obj[property] = StubSchema(propSchema)
+		obj[property] = stubSchema(propSchema, path+"/properties/"+jsonpointer.Escape(property))
 	}
 	return obj
 }
 
-func arrayStub(schema spec.Schema) []interface{} {
-	if schema.Items.Schema == nil {
+func arrayStub(schema spec.Schema, path string) []interface{} {
+	if schema.Items == nil || schema.Items.Schema == nil {
 		log.Printf("schema \"%v\" of type array missing items schema - stub will be an empty array", schema.ID)
 		return []interface{}{}
 	}
@@ -59,7 +183,7 @@ func arrayStub(schema spec.Schema) []interface{} {
 	size := randInt(0, 10)
 	items := make([]interface{}, size)
 	for i := 0; i < size; i++ {
-		items[i] = StubSchema(*schema.Items.Schema)
+		items[i] = stubSchema(*schema.Items.Schema, path+"/items")
 	}
 
 	return items
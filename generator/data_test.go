@@ -57,3 +57,34 @@ func TestStringStubEnum(t *testing.T) {
 
 	require.Subset(choices, []interface{}{result})
 }
+
+func TestMinimalStub(t *testing.T) {
+	require := require.New(t)
+
+	minimum := 3.0
+	minItems := int64(1)
+	schema := spec.Schema{
+		SchemaProps: spec.SchemaProps{
+			Type:     spec.StringOrArray{"object"},
+			Required: []string{"count", "tags", "kind", "id"},
+			Properties: map[string]spec.Schema{
+				"count": {SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"integer"}, Minimum: &minimum, ExclusiveMinimum: true}},
+				"tags": {SchemaProps: spec.SchemaProps{
+					Type:     spec.StringOrArray{"array"},
+					MinItems: &minItems,
+					Items:    &spec.SchemaOrArray{Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"boolean"}}}},
+				}},
+				"kind":     {SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"string"}, Enum: []interface{}{"cat", "dog"}}},
+				"id":       {SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"string"}, Format: "uuid"}},
+				"optional": {SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"string"}}},
+			},
+		},
+	}
+
+	require.Equal(map[string]interface{}{
+		"count": float64(4),
+		"tags":  []interface{}{false},
+		"kind":  "cat",
+		"id":    "00000000-0000-0000-0000-000000000000",
+	}, MinimalStub(schema))
+}
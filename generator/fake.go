@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/manveru/faker"
+)
+
+// fakes are the kinds of data that Fake can generate
+var fakes = map[string]func(*faker.Faker) string{
+	"name":          (*faker.Faker).Name,
+	"firstName":     (*faker.Faker).FirstName,
+	"lastName":      (*faker.Faker).LastName,
+	"email":         (*faker.Faker).Email,
+	"username":      (*faker.Faker).UserName,
+	"phone":         (*faker.Faker).PhoneNumber,
+	"url":           (*faker.Faker).URL,
+	"company":       (*faker.Faker).CompanyName,
+	"jobTitle":      (*faker.Faker).JobTitle,
+	"streetAddress": (*faker.Faker).StreetAddress,
+	"city":          (*faker.Faker).City,
+	"state":         (*faker.Faker).State,
+	"postCode":      (*faker.Faker).PostCode,
+	"country":       (*faker.Faker).Country,
+	"word": func(f *faker.Faker) string {
+		return f.Words(1, false)[0]
+	},
+	"sentence": func(f *faker.Faker) string {
+		return f.Sentence(4, false)
+	},
+	"paragraph": func(f *faker.Faker) string {
+		return f.Paragraph(3, false)
+	},
+}
+
+// the faker's random source and buffer aren't
+// safe to use from concurrent requests
+var (
+	fakerMutex sync.Mutex
+	fakeData   *faker.Faker
+)
+
+// Fake returns realistic looking random data of a kind such as
+// "name", "email" or "city". It's available to overlay templates
+// as {{ fake "name" }}.
+func Fake(kind string) (string, error) {
+	fake, ok := fakes[kind]
+	if !ok {
+		return "", fmt.Errorf("unknown fake data kind %q", kind)
+	}
+
+	fakerMutex.Lock()
+	defer fakerMutex.Unlock()
+	if fakeData == nil {
+		var err error
+		fakeData, err = faker.New("en")
+		if err != nil {
+			return "", err
+		}
+	}
+	return fake(fakeData), nil
+}
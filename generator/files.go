@@ -0,0 +1,45 @@
+package generator
+
+import (
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// loadFile reads the response's file relative to the overlay
+// directory. JSON files are used as the response content unless
+// the response is raw, anything else is served as is.
+func (response *Response) loadFile(dir string) error {
+	if response.File == "" {
+		return nil
+	}
+
+	path := response.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "reading overlay response file")
+	}
+	response.File = path
+
+	if response.ContentType == "" {
+		response.ContentType = mime.TypeByExtension(filepath.Ext(path))
+	}
+	if response.ContentType == "" {
+		response.ContentType = http.DetectContentType(content)
+	}
+
+	if isJSONMediaType(response.ContentType) && !response.Raw {
+		response.Content = string(content)
+		return nil
+	}
+
+	response.Raw = true
+	response.body = content
+	return nil
+}
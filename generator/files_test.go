@@ -0,0 +1,41 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStubResponseWithOverlayFiles(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{
+		Overlay: "testdata/files/overlay.yaml",
+	})
+	require.NoError(err)
+
+	response, err := stub.StubResponse(Request{Path: "/v1/pets", Method: "GET"})
+	require.NoError(err)
+	require.False(response.Raw)
+	require.Equal("application/json", response.MediaType)
+	require.Equal([]interface{}{map[string]interface{}{"id": float64(1), "name": "from a file"}}, response.Body)
+
+	response, err = stub.StubResponse(Request{Path: "/v1/pets/1", Method: "GET"})
+	require.NoError(err)
+	require.True(response.Raw)
+	require.Equal("image/png", response.MediaType)
+	require.Equal([]byte("\x89PNG\r\n\x1a\n\x00\x00"), response.Body)
+
+	response, err = stub.StubResponse(Request{Path: "/v1/pets/2", Method: "GET"})
+	require.NoError(err)
+	require.True(response.Raw)
+	require.Equal("application/pdf", response.MediaType)
+	require.Equal([]byte("%PDF-1.4 {{ not a template }}"), response.Body)
+}
+
+func TestLoadMissingOverlayFile(t *testing.T) {
+	require := require.New(t)
+
+	response := &Response{File: "missing.json"}
+	require.Error(response.load("testdata/files"))
+}
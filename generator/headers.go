@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// StubHeaders returns values for the headers declared on a response
+// along with any header values from the response overlay, which may be nil.
+// Overlay values replace generated values and null overlay values remove them.
+func StubHeaders(response spec.Response, responseOverlay *Response) map[string]string {
+	headers := map[string]string{}
+	for name, header := range response.Headers {
+		headers[name] = StubHeader(header)
+	}
+
+	if responseOverlay != nil {
+		for name, value := range responseOverlay.Headers {
+			for existing := range headers {
+				if strings.EqualFold(existing, name) {
+					delete(headers, existing)
+				}
+			}
+			if value != nil {
+				headers[name] = *value
+			}
+		}
+	}
+
+	return headers
+}
+
+// StubHeader returns a random value for a header
+// using it's type, format and enum
+func StubHeader(header spec.Header) string {
+	schema := spec.Schema{
+		SchemaProps: spec.SchemaProps{
+			Type:   spec.StringOrArray{header.Type},
+			Format: header.Format,
+			Enum:   header.Enum,
+		},
+	}
+	if header.Type == "array" && header.Items != nil {
+		schema.Items = &spec.SchemaOrArray{Schema: itemsSchema(header.Items)}
+	}
+
+	value := StubSchema(schema)
+
+	if items, ok := value.([]interface{}); ok {
+		values := make([]string, len(items))
+		for i, item := range items {
+			values[i] = headerText(item)
+		}
+		return strings.Join(values, collectionSeparator(header.CollectionFormat))
+	}
+	return headerText(value)
+}
+
+// itemsSchema converts the items of a non-body parameter
+// or header into a schema
+func itemsSchema(items *spec.Items) *spec.Schema {
+	schema := &spec.Schema{
+		SchemaProps: spec.SchemaProps{
+			Type:   spec.StringOrArray{items.Type},
+			Format: items.Format,
+			Enum:   items.Enum,
+		},
+	}
+	if items.Type == "array" && items.Items != nil {
+		schema.Items = &spec.SchemaOrArray{Schema: itemsSchema(items.Items)}
+	}
+	return schema
+}
+
+// collectionSeparator returns the separator for
+// a swagger collectionFormat. csv is the default.
+func collectionSeparator(collectionFormat string) string {
+	switch collectionFormat {
+	case "ssv":
+		return " "
+	case "tsv":
+		return "\t"
+	case "pipes":
+		return "|"
+	default:
+		return ","
+	}
+}
+
+func headerText(value interface{}) string {
+	if value, ok := value.([]byte); ok {
+		return string(value)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,38 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/go-openapi/spec"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStubHeaderEnum(t *testing.T) {
+	require := require.New(t)
+
+	header := spec.ResponseHeader().Typed("string", "").WithEnum("a", "b")
+
+	require.Contains([]string{"a", "b"}, StubHeader(*header))
+}
+
+func TestStubHeadersWithOverlay(t *testing.T) {
+	require := require.New(t)
+
+	response := spec.NewResponse().AddHeader("x-next", spec.ResponseHeader().Typed("string", ""))
+	next := "/v1/pets?page=2"
+	responseOverlay := &Response{Headers: map[string]*string{"x-next": &next}}
+
+	headers := StubHeaders(*response, responseOverlay)
+	require.Equal(map[string]string{"x-next": "/v1/pets?page=2"}, headers)
+}
+
+func TestStubHeadersRemovedByOverlay(t *testing.T) {
+	require := require.New(t)
+
+	response := spec.NewResponse().AddHeader("x-next", spec.ResponseHeader().Typed("string", ""))
+	responseOverlay := &Response{Headers: map[string]*string{"X-Next": nil}}
+
+	headers := StubHeaders(*response, responseOverlay)
+	require.Equal(map[string]string{}, headers)
+}
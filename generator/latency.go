@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LatencyExtension is the operation extension that
+// sets the latency of an operation in the spec
+const LatencyExtension = "x-mock-latency"
+
+// Latency describes how long a response should be delayed
+type Latency struct {
+	// Mode is one of "fixed", "uniform" or "normal"
+	Mode string
+	// Min is the fixed delay or the lower bound of a uniform delay
+	Min time.Duration
+	// Max is the upper bound of a uniform delay
+	Max time.Duration
+	// Mean and StdDev describe a normally distributed delay
+	Mean   time.Duration
+	StdDev time.Duration
+}
+
+var normalLatency = regexp.MustCompile(`^normal\(\s*([^,\s]+)\s*,\s*([^)\s]+)\s*\)$`)
+
+// ParseLatency parses a latency setting. The supported formats are
+// a fixed duration ("200ms"), a uniform range ("100ms-500ms") and a
+// normal distribution with a mean and standard deviation
+// ("normal(300ms,50ms)"). An empty value returns nil.
+func ParseLatency(value string) (*Latency, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	if match := normalLatency.FindStringSubmatch(value); match != nil {
+		mean, err := time.ParseDuration(match[1])
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing latency mean")
+		}
+		stdDev, err := time.ParseDuration(match[2])
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing latency standard deviation")
+		}
+		return &Latency{Mode: "normal", Mean: mean, StdDev: stdDev}, nil
+	}
+
+	if parts := strings.SplitN(value, "-", 2); len(parts) == 2 {
+		min, err := time.ParseDuration(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing latency lower bound")
+		}
+		max, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing latency upper bound")
+		}
+		if max < min {
+			return nil, fmt.Errorf("latency upper bound %v is less than lower bound %v", max, min)
+		}
+		return &Latency{Mode: "uniform", Min: min, Max: max}, nil
+	}
+
+	fixed, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing latency")
+	}
+	return &Latency{Mode: "fixed", Min: fixed}, nil
+}
+
+// Duration returns a delay from the latency's distribution.
+// Negative samples from a normal distribution are clamped to zero.
+func (latency *Latency) Duration() time.Duration {
+	switch latency.Mode {
+	case "uniform":
+		return latency.Min + time.Duration(rand.Int63n(int64(latency.Max-latency.Min)+1))
+	case "normal":
+		sample := rand.NormFloat64()*float64(latency.StdDev) + float64(latency.Mean)
+		return time.Duration(math.Max(0, sample))
+	default:
+		return latency.Min
+	}
+}
+
+// FindLatency returns the latency configured for an operation
+// by the overlay or the operation's x-mock-latency extension,
+// in that order of precedence. It returns nil if neither is set.
+func (stub *StubGenerator) FindLatency(path string, method string) (*Latency, error) {
+	return stub.current().FindLatency(path, method)
+}
+
+// FindLatency implements StubGenerator.FindLatency
+func (stub *stubState) FindLatency(path string, method string) (*Latency, error) {
+	if operationOverlay := stub.overlay.FindOperation(path, method); operationOverlay != nil && operationOverlay.Latency != "" {
+		return ParseLatency(operationOverlay.Latency)
+	}
+
+	operation, err := stub.FindOperation(path, method)
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := operation.Extensions.GetString(LatencyExtension); ok {
+		latency, err := ParseLatency(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %v of operation %v", LatencyExtension, operation.ID)
+		}
+		return latency, nil
+	}
+
+	return nil, nil
+}
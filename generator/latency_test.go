@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLatency(t *testing.T) {
+	require := require.New(t)
+
+	latency, err := ParseLatency("200ms")
+	require.NoError(err)
+	require.Equal(&Latency{Mode: "fixed", Min: 200 * time.Millisecond}, latency)
+	require.Equal(200*time.Millisecond, latency.Duration())
+
+	latency, err = ParseLatency("100ms-500ms")
+	require.NoError(err)
+	require.Equal(&Latency{Mode: "uniform", Min: 100 * time.Millisecond, Max: 500 * time.Millisecond}, latency)
+	duration := latency.Duration()
+	require.True(duration >= 100*time.Millisecond && duration <= 500*time.Millisecond)
+
+	latency, err = ParseLatency("normal(300ms, 50ms)")
+	require.NoError(err)
+	require.Equal(&Latency{Mode: "normal", Mean: 300 * time.Millisecond, StdDev: 50 * time.Millisecond}, latency)
+
+	latency, err = ParseLatency("")
+	require.NoError(err)
+	require.Nil(latency)
+
+	_, err = ParseLatency("500ms-100ms")
+	require.Error(err)
+
+	_, err = ParseLatency("soon")
+	require.Error(err)
+}
+
+func TestFindLatencyOverlayBeatsExtension(t *testing.T) {
+	require := require.New(t)
+
+	for _, c := range []struct {
+		overlays []string
+		expected time.Duration
+	}{
+		{nil, time.Second},
+		{[]string{"testdata/latency.yaml"}, 2 * time.Second},
+	} {
+		stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{Overlays: c.overlays})
+		require.NoError(err)
+
+		operation, err := stub.FindOperation("/v1/pets", "GET")
+		require.NoError(err)
+		operation.AddExtension(LatencyExtension, "1s")
+
+		latency, err := stub.FindLatency("/v1/pets", "GET")
+		require.NoError(err)
+		require.Equal(c.expected, latency.Min)
+	}
+}
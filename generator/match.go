@@ -0,0 +1,263 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/jsonpointer"
+	"github.com/pkg/errors"
+)
+
+// Match holds the conditions that a request must meet for a response
+// overlay to be used. Every condition must be met.
+type Match struct {
+	// Query conditions keyed by query parameter name
+	Query map[string]Condition `yaml:"query"`
+	// Headers conditions keyed by header name
+	Headers map[string]Condition `yaml:"headers"`
+	// Body conditions keyed by a JSON Pointer ("/pet/name")
+	// or a JSONPath ("$.pet.name") into the parsed request body
+	Body map[string]Condition `yaml:"body"`
+}
+
+// Condition is a predicate on a single request value. In yaml
+// it's either a plain value to compare for equality or a mapping
+// with one or more of equals, regex and exists.
+type Condition struct {
+	Equals interface{}
+	Regex  string
+	Exists *bool
+
+	regex *regexp.Regexp
+}
+
+// UnmarshalYAML reads a condition from a plain value or from
+// a mapping of equals, regex and exists
+func (condition *Condition) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	if mapping, ok := raw.(map[interface{}]interface{}); ok && isConditionMapping(mapping) {
+		var fields struct {
+			Equals interface{} `yaml:"equals"`
+			Regex  string      `yaml:"regex"`
+			Exists *bool       `yaml:"exists"`
+		}
+		if err := unmarshal(&fields); err != nil {
+			return err
+		}
+		condition.Equals = normalizeYAML(fields.Equals)
+		condition.Regex = fields.Regex
+		condition.Exists = fields.Exists
+	} else {
+		condition.Equals = normalizeYAML(raw)
+	}
+
+	return condition.compile()
+}
+
+// compile compiles the condition's regex. Conditions read from
+// yaml are compiled already but conditions built in code
+// must be compiled before they're used.
+func (condition *Condition) compile() error {
+	condition.regex = nil
+	if condition.Regex == "" {
+		return nil
+	}
+	regex, err := regexp.Compile(condition.Regex)
+	if err != nil {
+		return errors.Wrap(err, "compiling match regex")
+	}
+	condition.regex = regex
+	return nil
+}
+
+func isConditionMapping(mapping map[interface{}]interface{}) bool {
+	for key := range mapping {
+		switch key {
+		case "equals", "regex", "exists":
+		default:
+			return false
+		}
+	}
+	return len(mapping) != 0
+}
+
+// matches reports whether a value meets the condition. found
+// is false when the value isn't present in the request.
+func (condition Condition) matches(value interface{}, found bool) (bool, error) {
+	if condition.Regex != "" && condition.regex == nil {
+		return false, fmt.Errorf("match regex %q hasn't been compiled", condition.Regex)
+	}
+
+	if condition.Exists != nil && *condition.Exists != found {
+		return false, nil
+	}
+	if !found {
+		return condition.Exists != nil, nil
+	}
+
+	if condition.regex != nil && !condition.regex.MatchString(matchText(value)) {
+		return false, nil
+	}
+
+	if condition.Equals != nil {
+		if text, ok := value.(string); ok {
+			// query parameters and headers are strings
+			// so compare them to the text of the condition
+			return text == matchText(condition.Equals), nil
+		}
+		return reflect.DeepEqual(normalizeJSON(value), normalizeJSON(condition.Equals)), nil
+	}
+
+	return true, nil
+}
+
+// Matches reports whether the request meets every condition
+func (match *Match) Matches(request Request) (bool, error) {
+	if match == nil {
+		return true, nil
+	}
+
+	for name, condition := range match.Query {
+		values, found := request.Query[name]
+		var value interface{}
+		if found && len(values) != 0 {
+			value = values[0]
+		}
+		if ok, err := condition.matches(value, found); !ok || err != nil {
+			return false, errors.Wrapf(err, "query parameter %v", name)
+		}
+	}
+
+	for name, condition := range match.Headers {
+		values, found := request.Header[http.CanonicalHeaderKey(name)]
+		var value interface{}
+		if found && len(values) != 0 {
+			value = values[0]
+		}
+		if ok, err := condition.matches(value, found); !ok || err != nil {
+			return false, errors.Wrapf(err, "header %v", name)
+		}
+	}
+
+	for path, condition := range match.Body {
+		value, err := lookupBody(request.Body, path)
+		if ok, err := condition.matches(value, err == nil); !ok || err != nil {
+			return false, errors.Wrapf(err, "body %v", path)
+		}
+	}
+
+	return true, nil
+}
+
+// lookupBody finds a value in the parsed request body using
+// a JSON Pointer or a simple JSONPath ($.a.b[0].c)
+func lookupBody(body interface{}, path string) (interface{}, error) {
+	if body == nil {
+		return nil, fmt.Errorf("request has no body")
+	}
+
+	if strings.HasPrefix(path, "$") {
+		return lookupJSONPath(body, path)
+	}
+
+	pointer, err := jsonpointer.New(path)
+	if err != nil {
+		return nil, err
+	}
+	value, _, err := pointer.Get(body)
+	return value, err
+}
+
+var jsonPathToken = regexp.MustCompile(`\.([^.\[\]]+)|\[(\d+)\]|\['([^']*)'\]`)
+
+func lookupJSONPath(body interface{}, path string) (interface{}, error) {
+	rest := strings.TrimPrefix(path, "$")
+	value := body
+	for len(rest) > 0 {
+		match := jsonPathToken.FindStringSubmatchIndex(rest)
+		if match == nil || match[0] != 0 {
+			return nil, fmt.Errorf("unsupported JSONPath %q", path)
+		}
+
+		var key string
+		index := -1
+		switch {
+		case match[2] != -1:
+			key = rest[match[2]:match[3]]
+		case match[4] != -1:
+			index, _ = strconv.Atoi(rest[match[4]:match[5]])
+		default:
+			key = rest[match[6]:match[7]]
+		}
+		rest = rest[match[1]:]
+
+		if index != -1 {
+			items, ok := value.([]interface{})
+			if !ok || index >= len(items) {
+				return nil, fmt.Errorf("%v not found in request body", path)
+			}
+			value = items[index]
+			continue
+		}
+
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v not found in request body", path)
+		}
+		if value, ok = obj[key]; !ok {
+			return nil, fmt.Errorf("%v not found in request body", path)
+		}
+	}
+	return value, nil
+}
+
+func matchText(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	content, _ := json.Marshal(normalizeJSON(value))
+	return string(content)
+}
+
+// normalizeYAML converts the map[interface{}]interface{} values
+// produced by the yaml library into map[string]interface{} values
+func normalizeYAML(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		obj := map[string]interface{}{}
+		for key, item := range value {
+			obj[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return obj
+	case []interface{}:
+		items := make([]interface{}, len(value))
+		for i, item := range value {
+			items[i] = normalizeYAML(item)
+		}
+		return items
+	}
+	return value
+}
+
+// normalizeJSON round trips a value through JSON so that
+// values can be compared regardless of their Go types (i.e. int vs float64)
+func normalizeJSON(value interface{}) interface{} {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(content, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
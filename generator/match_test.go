@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const matchOverlay = `
+paths:
+  /v1/pets:
+    get:
+      responses:
+        200:
+          - match:
+              query:
+                limit: "1"
+            content: '[{"name": "one"}]'
+          - match:
+              headers:
+                x-tenant:
+                  regex: ^acme-
+            content: '[{"name": "acme"}]'
+          - content: '[{"name": "fallback"}]'
+          - priority: 10
+            match:
+              query:
+                debug:
+                  exists: true
+            content: '[{"name": "debug"}]'
+    post:
+      responses:
+        201:
+          - match:
+              body:
+                /name: rex
+                $.tags[0]: good
+            content: '{"name": "rex"}'
+          - match:
+              body:
+                /age: 3
+            content: '{"name": "three"}'
+`
+
+func TestOverlayMatching(t *testing.T) {
+	require := require.New(t)
+
+	overlay := Overlay{}
+	require.NoError(yaml.Unmarshal([]byte(matchOverlay), &overlay))
+
+	find := func(request Request, statusCode int) string {
+		response, err := overlay.FindResponse(request, statusCode)
+		if err != nil {
+			return ""
+		}
+		return response.Content
+	}
+
+	request := Request{Path: "/v1/pets", Method: "GET"}
+	require.Equal(`[{"name": "fallback"}]`, find(request, 200))
+
+	request.Query = url.Values{"limit": {"1"}}
+	require.Equal(`[{"name": "one"}]`, find(request, 200))
+
+	request.Query = url.Values{"limit": {"1"}, "debug": {""}}
+	require.Equal(`[{"name": "debug"}]`, find(request, 200))
+
+	request.Query = nil
+	request.Header = http.Header{"X-Tenant": {"acme-1"}}
+	require.Equal(`[{"name": "acme"}]`, find(request, 200))
+
+	request = Request{Path: "/v1/pets", Method: "POST"}
+	require.Equal("", find(request, 201))
+
+	request.Body = map[string]interface{}{"name": "rex", "tags": []interface{}{"good"}}
+	require.Equal(`{"name": "rex"}`, find(request, 201))
+
+	request.Body = map[string]interface{}{"name": "rex", "tags": []interface{}{"bad"}}
+	require.Equal("", find(request, 201))
+
+	request.Body = map[string]interface{}{"age": float64(3)}
+	require.Equal(`{"name": "three"}`, find(request, 201))
+}
+
+func TestOverlaySingleResponse(t *testing.T) {
+	require := require.New(t)
+
+	overlay := Overlay{}
+	require.NoError(yaml.Unmarshal([]byte(`
+paths:
+  /v1/pets:
+    get:
+      responses:
+        200:
+          content: '[]'
+`), &overlay))
+
+	response, err := overlay.FindResponse(Request{Path: "/v1/pets", Method: "GET"}, 200)
+	require.NoError(err)
+	require.Equal("[]", response.Content)
+}
+
+func TestMatchConditionRegexError(t *testing.T) {
+	require := require.New(t)
+
+	match := Match{}
+	err := yaml.Unmarshal([]byte("query:\n  name:\n    regex: '('\n"), &match)
+	require.Error(err)
+}
+
+func TestMatchConditionBuiltInCode(t *testing.T) {
+	require := require.New(t)
+
+	request := Request{Query: url.Values{"name": {"rex"}}}
+
+	match := Match{Query: map[string]Condition{"name": {Regex: "^r"}}}
+	_, err := match.Matches(request)
+	require.Error(err)
+
+	condition := match.Query["name"]
+	require.NoError(condition.compile())
+	match.Query["name"] = condition
+	ok, err := match.Matches(request)
+	require.NoError(err)
+	require.True(ok)
+
+	condition = Condition{Regex: "("}
+	require.Error(condition.compile())
+}
@@ -0,0 +1,207 @@
+package generator
+
+import (
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MediaType is a parsed media type such as
+// "application/json; charset=utf-8"
+type MediaType struct {
+	Type    string
+	Subtype string
+	Params  map[string]string
+}
+
+// ParseMediaType parses a Content-Type style value into a MediaType.
+// Types and parameter names are lowercased. A bare "*" is treated as "*/*".
+func ParseMediaType(value string) (MediaType, error) {
+	if strings.TrimSpace(value) == "*" {
+		value = "*/*"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		return MediaType{}, err
+	}
+
+	parts := strings.SplitN(mediaType, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return MediaType{}, fmt.Errorf("invalid media type %q", value)
+	}
+
+	return MediaType{
+		Type:    parts[0],
+		Subtype: parts[1],
+		Params:  params,
+	}, nil
+}
+
+// String returns the media type without parameters i.e. "application/json"
+func (mediaType MediaType) String() string {
+	return mediaType.Type + "/" + mediaType.Subtype
+}
+
+// Suffix returns the structured syntax suffix of the subtype,
+// i.e. "json" for "application/vnd.api+json", or an empty string
+func (mediaType MediaType) Suffix() string {
+	if i := strings.LastIndex(mediaType.Subtype, "+"); i != -1 {
+		return mediaType.Subtype[i+1:]
+	}
+	return ""
+}
+
+// Matches reports whether the media type is accepted by the pattern.
+// The pattern may use wildcards ("*/*", "application/*"), a pattern of
+// "application/json" accepts any "+json" subtype and every parameter in the
+// pattern must be present with the same value in the media type.
+func (mediaType MediaType) Matches(pattern MediaType) bool {
+	if pattern.Type != "*" && pattern.Type != mediaType.Type {
+		return false
+	}
+
+	if pattern.Subtype != "*" && pattern.Subtype != mediaType.Subtype {
+		// application/json matches application/vnd.api+json
+		if mediaType.Suffix() == "" || mediaType.Suffix() != pattern.Subtype {
+			return false
+		}
+	}
+
+	for name, value := range pattern.Params {
+		if !strings.EqualFold(mediaType.Params[name], value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MediaTypeMatchesAny reports whether the media type value
+// is matched by at least one of the patterns
+func MediaTypeMatchesAny(value string, patterns []string) bool {
+	mediaType, err := ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	for _, item := range patterns {
+		pattern, err := ParseMediaType(item)
+		if err != nil {
+			continue
+		}
+		if mediaType.Matches(pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNotAcceptable is returned by NegotiateMediaType when none of
+// the produced media types are acceptable to the client
+var ErrNotAcceptable = errors.New("no acceptable media type")
+
+// DefaultMediaType is used when an operation doesn't
+// declare any produced media types
+const DefaultMediaType = "application/json"
+
+// acceptRange is a single media range from an Accept header
+type acceptRange struct {
+	mediaType MediaType
+	quality   float64
+}
+
+// NegotiateMediaType picks the media type from produces that best
+// satisfies the Accept header value. The most specific matching
+// range decides the quality of each produced media type, and ties are broken
+// by the order of produces. An empty Accept header accepts anything.
+func NegotiateMediaType(accept string, produces []string) (string, error) {
+	if len(produces) == 0 {
+		produces = []string{DefaultMediaType}
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return produces[0], nil
+	}
+
+	ranges := parseAccept(accept)
+
+	best := ""
+	bestQuality := 0.0
+	for _, item := range produces {
+		produced, err := ParseMediaType(item)
+		if err != nil {
+			continue
+		}
+
+		quality := -1.0
+		specificity := -1
+		for _, r := range ranges {
+			if !produced.Matches(r.mediaType) && !r.mediaType.Matches(produced) {
+				continue
+			}
+			if s := r.mediaType.specificity(); s > specificity {
+				specificity = s
+				quality = r.quality
+			}
+		}
+
+		if quality > bestQuality {
+			best = item
+			bestQuality = quality
+		}
+	}
+
+	if best == "" {
+		return "", errors.Wrapf(ErrNotAcceptable, "accept %q does not match any of %v", accept, produces)
+	}
+	return best, nil
+}
+
+// parseAccept parses an Accept header into it's media ranges.
+// Invalid ranges are ignored.
+func parseAccept(accept string) []acceptRange {
+	ranges := []acceptRange{}
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, err := ParseMediaType(item)
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := mediaType.Params["q"]; ok {
+			if value, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = value
+			}
+			delete(mediaType.Params, "q")
+		}
+
+		ranges = append(ranges, acceptRange{mediaType, quality})
+	}
+	return ranges
+}
+
+// specificity ranks media ranges so that "text/plain;format=flowed"
+// beats "text/plain" which beats "text/*" which beats "*/*"
+func (mediaType MediaType) specificity() int {
+	specificity := 0
+	if mediaType.Type != "*" {
+		specificity++
+	}
+	if mediaType.Subtype != "*" {
+		specificity++
+	}
+	return specificity*100 + len(mediaType.Params)
+}
+
+// isJSONMediaType reports whether a media type is JSON
+// or has a +json suffix
+func isJSONMediaType(value string) bool {
+	mediaType, err := ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	return mediaType.Subtype == "json" || mediaType.Suffix() == "json"
+}
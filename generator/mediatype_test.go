@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMediaTypeMatchesAny(t *testing.T) {
+	require := require.New(t)
+
+	require.True(MediaTypeMatchesAny("application/json; charset=utf-8", []string{"application/json"}))
+	require.True(MediaTypeMatchesAny("application/json", []string{"application/*"}))
+	require.True(MediaTypeMatchesAny("text/plain", []string{"*/*"}))
+	require.True(MediaTypeMatchesAny("application/vnd.api+json", []string{"application/json"}))
+	require.True(MediaTypeMatchesAny("text/plain; charset=UTF-8", []string{"text/plain; charset=utf-8"}))
+
+	require.False(MediaTypeMatchesAny("text/plain", []string{"application/json"}))
+	require.False(MediaTypeMatchesAny("text/plain", []string{"text/plain; charset=utf-8"}))
+	require.False(MediaTypeMatchesAny("not a media type", []string{"*/*"}))
+}
+
+func TestExpandMediaTypes(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{})
+	require.NoError(err)
+
+	operation, err := stub.FindOperation("/v1/pets", "POST")
+	require.NoError(err)
+
+	require.Equal([]string{"application/json"}, operation.Consumes)
+	require.Equal([]string{"application/json"}, operation.Produces)
+}
+
+func TestNegotiateMediaType(t *testing.T) {
+	require := require.New(t)
+
+	produces := []string{"application/json", "application/xml", "text/csv"}
+
+	mediaType, err := NegotiateMediaType("", produces)
+	require.NoError(err)
+	require.Equal("application/json", mediaType)
+
+	mediaType, err = NegotiateMediaType("text/*;q=0.5, application/xml", produces)
+	require.NoError(err)
+	require.Equal("application/xml", mediaType)
+
+	mediaType, err = NegotiateMediaType("*/*;q=0.1, text/csv", produces)
+	require.NoError(err)
+	require.Equal("text/csv", mediaType)
+
+	mediaType, err = NegotiateMediaType("application/*, application/json;q=0", produces)
+	require.NoError(err)
+	require.Equal("application/xml", mediaType)
+
+	_, err = NegotiateMediaType("image/png", produces)
+	require.Equal(ErrNotAcceptable, errors.Cause(err))
+}
@@ -0,0 +1,193 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LoadOverlays loads overlay files and directories of overlay files
+// and merges them into one overlay. Files are merged in the order they're
+// given and the files in a directory are merged in lexical order of their
+// path. A later file replaces the responses (by path, method and status code)
+// and latencies of an earlier file and each replacement is reported by
+// Conflicts.
+func LoadOverlays(paths []string) (*Overlay, error) {
+	files, dirs, err := expandOverlayPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &Overlay{files: dirs}
+	for _, file := range files {
+		overlay, err := loadOverlayFile(file, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		merged.merge(overlay)
+	}
+
+	if err := merged.compile(); err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+// expandOverlayPaths replaces directories with the .yaml and .yml
+// files inside them. The directories are also returned.
+func expandOverlayPaths(paths []string) ([]string, []string, error) {
+	files := []string{}
+	dirs := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "reading overlay file")
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		found := []string{}
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				dirs = append(dirs, file)
+			}
+			if ext := filepath.Ext(file); !info.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				found = append(found, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "reading overlay directory")
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, dirs, nil
+}
+
+// Conflicts returns a message for every response or latency
+// that replaced one from another overlay file
+func (overlay *Overlay) Conflicts() []error {
+	return append([]error{}, overlay.conflicts...)
+}
+
+// setSource records the file that every response
+// and latency of the overlay came from
+func (overlay *Overlay) setSource(file string) {
+	overlay.sources = map[string]string{}
+	for path, pathItem := range overlay.Paths {
+		for method, operation := range pathItem.operations() {
+			if operation.Latency != "" {
+				overlay.sources[sourceKey(method, path, "latency")] = file
+			}
+			for statusCode := range operation.Responses {
+				overlay.sources[sourceKey(method, path, statusCode)] = file
+			}
+		}
+	}
+}
+
+func sourceKey(method string, path string, part interface{}) string {
+	return fmt.Sprintf("%v %v %v", method, path, part)
+}
+
+// merge copies the paths of other into the overlay, replacing
+// the responses and latencies that they both have
+func (overlay *Overlay) merge(other *Overlay) {
+	if overlay.Paths == nil {
+		overlay.Paths = map[string]PathItem{}
+	}
+	if overlay.sources == nil {
+		overlay.sources = map[string]string{}
+	}
+	overlay.conflicts = append(overlay.conflicts, other.conflicts...)
+	overlay.files = append(overlay.files, other.files...)
+
+	replace := func(key string) {
+		if previous, ok := overlay.sources[key]; ok && previous != other.sources[key] {
+			overlay.conflicts = append(overlay.conflicts, fmt.Errorf("overlay %v from %v replaces the one from %v", key, other.sources[key], previous))
+		}
+		overlay.sources[key] = other.sources[key]
+	}
+
+	for path, otherItem := range other.Paths {
+		pathItem := overlay.Paths[path]
+		for method, otherOperation := range otherItem.operations() {
+			operation := pathItem.operations()[method]
+			if operation == nil {
+				operation = &Operation{}
+				pathItem.setOperation(method, operation)
+			}
+
+			if otherOperation.Latency != "" {
+				replace(sourceKey(method, path, "latency"))
+				operation.Latency = otherOperation.Latency
+			}
+
+			if len(otherOperation.Responses) != 0 && operation.Responses == nil {
+				operation.Responses = map[int]Responses{}
+			}
+			for statusCode, responses := range otherOperation.Responses {
+				replace(sourceKey(method, path, statusCode))
+				operation.Responses[statusCode] = responses
+			}
+		}
+		overlay.Paths[path] = pathItem
+	}
+}
+
+// responseFiles returns the files that
+// the overlay's responses were read from
+func (overlay *Overlay) responseFiles() []string {
+	files := []string{}
+	for _, pathItem := range overlay.Paths {
+		for _, operation := range pathItem.operations() {
+			for _, responses := range operation.Responses {
+				for _, response := range responses {
+					if response.File != "" {
+						files = append(files, response.File)
+					}
+					if response.Sequence != nil {
+						for _, step := range response.Sequence.Responses {
+							if step.File != "" {
+								files = append(files, step.File)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// setOperation sets the operation overlay for an HTTP method
+func (pathItem *PathItem) setOperation(method string, operation *Operation) {
+	switch strings.ToUpper(method) {
+	case "GET":
+		pathItem.Get = operation
+	case "PUT":
+		pathItem.Put = operation
+	case "POST":
+		pathItem.Post = operation
+	case "PATCH":
+		pathItem.Patch = operation
+	case "DELETE":
+		pathItem.Delete = operation
+	case "OPTIONS":
+		pathItem.Options = operation
+	case "HEAD":
+		pathItem.Head = operation
+	}
+}
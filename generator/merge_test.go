@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOverlays(t *testing.T) {
+	require := require.New(t)
+
+	overlay, err := LoadOverlays([]string{"testdata/merge/base.yaml", "testdata/merge/team"})
+	require.NoError(err)
+
+	operation := overlay.FindOperation("/v1/pets", "GET")
+	require.NotNil(operation)
+	require.Equal("10ms", operation.Latency)
+	require.Equal(`[{"name": "team a"}]`, operation.Responses[200][0].Content)
+	require.Equal(`{"message": "shared"}`, operation.Responses[404][0].Content)
+
+	operation = overlay.FindOperation("/v1/pets/1", "GET")
+	require.NotNil(operation)
+	require.Equal(`[{"name": "team b"}]`, operation.Responses[200][0].Content)
+
+	messages := []string{}
+	for _, err := range overlay.Conflicts() {
+		messages = append(messages, err.Error())
+	}
+	require.Equal([]string{
+		"overlay GET /v1/pets 200 from testdata/merge/base.yaml replaces the one from testdata/merge/shared.yaml",
+		"overlay GET /v1/pets 200 from testdata/merge/team/a.yaml replaces the one from testdata/merge/base.yaml",
+	}, messages)
+}
+
+func TestLoadOverlaysWithIncludeCycle(t *testing.T) {
+	require := require.New(t)
+
+	_, err := LoadOverlays([]string{"testdata/merge/cycle.yaml"})
+	require.Error(err)
+	require.Contains(err.Error(), "includes itself")
+}
+
+func TestMergeDeleteOperation(t *testing.T) {
+	require := require.New(t)
+
+	overlay := &Overlay{}
+	overlay.merge(&Overlay{Paths: map[string]PathItem{
+		"/v1/pets/1": {Delete: &Operation{Latency: "10ms"}},
+	}})
+
+	require.NotNil(overlay.FindOperation("/v1/pets/1", "DELETE"))
+}
+
+func TestConflictsAreNotValidationErrors(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("testdata/merge-spec.yaml", StubGeneratorOptions{
+		Overlays: []string{"testdata/merge/base.yaml"},
+	})
+	require.NoError(err)
+
+	require.Len(stub.OverlayConflicts(), 1)
+	require.Empty(stub.ValidateOverlay())
+}
@@ -1,10 +1,13 @@
 package generator
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"path"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/go-openapi/loads"
 	"github.com/go-openapi/spec"
@@ -13,30 +16,118 @@ import (
 
 // StubGeneratorOptions that can configure the stub generator
 type StubGeneratorOptions struct {
-	Overlay  string
+	Overlay string
+	// Overlays are overlay files or directories that
+	// are merged, in order, after Overlay
+	Overlays []string
 	BasePath string
+	// StrictOverlay makes Reload keep the previous version
+	// if the new overlay doesn't match the spec
+	StrictOverlay bool
 }
 
 // StubGenerator is the main type used to interact with this
 // library's feature set
 type StubGenerator struct {
+	urlOrPath string
+	options   StubGeneratorOptions
+
+	// state is replaced as a whole by Reload so that
+	// every call sees a single version of the spec and overlay
+	mutex sync.RWMutex
+	state *stubState
+}
+
+// stubState is the spec and overlay that a
+// StubGenerator was loaded from
+type stubState struct {
 	spec    spec.Swagger
 	overlay Overlay
+	ranged  rangedResponses
+	// sequences counts the responses served by overlay sequences
+	sequences *sequenceCounters
+	// files are the local files that the state was loaded from
+	files []string
 }
 
 // NewStubGenerator loads an OpenAPI spec from the given url/path
 // and returns a StubGenerator
 func NewStubGenerator(urlOrPath string, options StubGeneratorOptions) (*StubGenerator, error) {
-	document, err := loads.Spec(urlOrPath)
+	state, err := loadStubState(urlOrPath, options)
+	if err != nil {
+		return nil, err
+	}
+
+	stub := &StubGenerator{
+		urlOrPath: urlOrPath,
+		options:   options,
+		state:     state,
+	}
+
+	return stub, nil
+}
+
+// current returns the latest state of the generator
+func (stub *StubGenerator) current() *stubState {
+	stub.mutex.RLock()
+	defer stub.mutex.RUnlock()
+	return stub.state
+}
+
+// StubResponse returns a response for a given Operation in the OpenAPI spec
+// with a body that matches the schema. The Operation is determined by the
+// request's path and method.
+func (stub *StubGenerator) StubResponse(request Request) (*StubbedResponse, error) {
+	return stub.current().StubResponse(request)
+}
+
+// FindOperation returns the best matching OpenAPI operation
+// from the Spec given an HTTP Request
+func (stub *StubGenerator) FindOperation(httpPath string, httpMethod string) (*spec.Operation, error) {
+	return stub.current().FindOperation(httpPath, httpMethod)
+}
+
+// FindRoute returns the best matching OpenAPI path and operation
+// from the Spec given an HTTP Request, along with the values of
+// any path parameters
+func (stub *StubGenerator) FindRoute(httpPath string, httpMethod string) (*Route, error) {
+	return stub.current().FindRoute(httpPath, httpMethod)
+}
+
+// FindPathItem returns the best matching OpenAPI path from the Spec
+// for an HTTP request path. The returned Route has no Operation.
+func (stub *StubGenerator) FindPathItem(httpPath string) (*Route, error) {
+	return stub.current().FindPathItem(httpPath)
+}
+
+// FindResponse returns the response for the preferred status code
+// or when there's no preference, the response with the lowest
+// HTTP status code (i.e. success codes over error codes).
+// Ranged status codes (2XX) are used when there's no exact match
+// and the default response is used as a last resort.
+func (stub *StubGenerator) FindResponse(operation *spec.Operation, preference ResponsePreference) (*spec.Response, *int, error) {
+	return stub.current().FindResponse(operation, preference)
+}
+
+// loadStubState loads the spec and overlays
+func loadStubState(urlOrPath string, options StubGeneratorOptions) (*stubState, error) {
+	original, err := loads.Spec(urlOrPath)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to load input file")
 	}
 
-	document, err = document.Expanded()
+	document, err := original.Expanded()
 	if err != nil {
 		return nil, errors.Wrap(err, "expanding spec refs")
 	}
 
+	// go-openapi drops ranged status codes like 2XX
+	// so we read them from the original document
+	ranged, err := LoadRangedResponses(original, document)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading ranged responses")
+	}
+
 	// the openapi libraries suggest that the base path is
 	// prefixed to paths: https://godoc.org/github.com/go-openapi/spec#Paths
 	// but it doesn't seem to be happening in practice.
@@ -45,9 +136,16 @@ func NewStubGenerator(urlOrPath string, options StubGeneratorOptions) (*StubGene
 
 	ExpandOperationIDs(document)
 
-	var overlay *Overlay
+	ExpandMediaTypes(document)
+
+	overlays := options.Overlays
 	if options.Overlay != "" {
-		overlay, err = LoadOverlayFile(options.Overlay)
+		overlays = append([]string{options.Overlay}, overlays...)
+	}
+
+	var overlay *Overlay
+	if len(overlays) != 0 {
+		overlay, err = LoadOverlays(overlays)
 		if err != nil {
 			return nil, errors.Wrap(err, "loading overlay")
 		}
@@ -56,56 +154,288 @@ func NewStubGenerator(urlOrPath string, options StubGeneratorOptions) (*StubGene
 		overlay = &tmp
 	}
 
-	stub := &StubGenerator{
-		spec:    *document.Spec(),
-		overlay: *overlay,
+	files := overlay.files
+	if _, err := os.Stat(urlOrPath); err == nil {
+		files = append([]string{urlOrPath}, files...)
 	}
 
-	return stub, nil
+	state := &stubState{
+		spec:      *document.Spec(),
+		overlay:   *overlay,
+		ranged:    ranged,
+		sequences: &sequenceCounters{},
+		files:     files,
+	}
+
+	return state, nil
 }
 
-// StubResponse returns data that matches the schema for a given Operation
-// in the OpenAPI spec. The Operation is determined by a path and method
-func (stub *StubGenerator) StubResponse(path string, method string) (interface{}, error) {
-	operation, err := stub.FindOperation(path, method)
+// ErrNoPreferredResponse is returned when the operation doesn't
+// define the response that the client prefers
+var ErrNoPreferredResponse = errors.New("no response matches the preference")
+
+// StubResponse implements StubGenerator.StubResponse
+func (stub *stubState) StubResponse(request Request) (*StubbedResponse, error) {
+	route, err := stub.FindRoute(request.Path, request.Method)
 	if err != nil {
 		return nil, errors.Wrap(err, "finding operation from path and method")
 	}
+	operation := route.Operation
+	if request.Params == nil {
+		request.Params = route.Params
+	}
 
-	response, statusCode, err := stub.FindResponse(operation)
+	response, statusCode, err := stub.FindResponse(operation, request.Preference)
 	if err != nil {
 		return nil, errors.Wrap(err, "finding response for operation")
 	}
 
+	stubbed := &StubbedResponse{
+		StatusCode: *statusCode,
+	}
+
+	// variables from overlay path templates
+	// and regexps are available to templates
+	if _, vars := stub.overlay.findOperation(request.Path, request.Method); len(vars) != 0 {
+		params := map[string]string{}
+		for name, value := range request.Params {
+			params[name] = value
+		}
+		for name, value := range vars {
+			params[name] = value
+		}
+		request.Params = params
+	}
+
+	// overlays are rendered once so that template
+	// functions give the same values to the headers and body
+	var responseOverlay *Response
+	undefinedStatus := false
+	found, err := stub.overlay.FindResponse(request, *statusCode)
+	if err != nil && errors.Cause(err) != ErrNoOverlay {
+		return nil, errors.Wrapf(err, "overlay for %v response of operation %s", *statusCode, operation.ID)
+	}
+	if found != nil {
+		if found.Sequence != nil {
+			step := stub.sequences.next(found.Sequence)
+			found = &step
+		}
+		rendered, err := RenderResponse(*found, NewTemplateData(request, stub.spec.Definitions))
+		if err != nil {
+			return nil, errors.Wrapf(err, "overlay for %v response of operation %s", *statusCode, operation.ID)
+		}
+		responseOverlay = &rendered
+
+		if responseOverlay.Status != 0 && responseOverlay.Status != *statusCode {
+			forced := responseOverlay.Status
+			response, _, err = stub.FindResponse(operation, ResponsePreference{StatusCode: forced})
+			if err != nil {
+				// the overlay is the whole response when
+				// the spec doesn't define the status code
+				response = &spec.Response{}
+				undefinedStatus = true
+			}
+			statusCode = &forced
+			stubbed.StatusCode = forced
+		}
+
+		stubbed.Delay, err = ParseLatency(responseOverlay.Delay)
+		if err != nil {
+			return nil, errors.Wrapf(err, "overlay for %v response of operation %s", *statusCode, operation.ID)
+		}
+	}
+
+	stubbed.Headers = StubHeaders(*response, responseOverlay)
+
+	if responseOverlay != nil && responseOverlay.Raw {
+		return stub.rawResponse(stubbed, request, operation, *responseOverlay)
+	}
+
+	if undefinedStatus {
+		return stub.overlayResponse(stubbed, request, operation, *responseOverlay)
+	}
+
+	if !HasBody(*statusCode, *response) {
+		return stubbed, nil
+	}
+
+	stubbed.MediaType, err = NegotiateMediaType(request.Accept, operation.Produces)
+	if err != nil {
+		return nil, err
+	}
+	stubbed.Schema = response.Schema
+
+	if request.Preference.Example != "" {
+		example, ok := response.Examples[request.Preference.Example]
+		if !ok {
+			return nil, errors.Wrapf(ErrNoPreferredResponse, "no %v example for %v response of operation %s", request.Preference.Example, *statusCode, operation.ID)
+		}
+		stubbed.Body = example
+		return stubbed, nil
+	}
+
 	stubbedData := StubSchema(*response.Schema)
 
-	if responseOverlay, err := stub.overlay.FindResponse(path, method, *statusCode); err == nil {
-		ApplyResponseOverlay(*responseOverlay, &stubbedData)
+	if responseOverlay != nil {
+		if err := ApplyResponseOverlay(*responseOverlay, &stubbedData); err != nil {
+			return nil, errors.Wrapf(err, "overlay for %v response of operation %s", *statusCode, operation.ID)
+		}
 	}
 
-	return stubbedData, nil
+	stubbed.Body = stubbedData
+	return stubbed, nil
 }
 
-// FindOperation returns the best matching OpenAPI operation
-// from the Spec given an HTTP Request
-func (stub *StubGenerator) FindOperation(httpPath string, httpMethod string) (*spec.Operation, error) {
-	// for every path, calculate a match score
-	// more matching path params means a higher score, 1 point per matching path param
-	scores := make(map[string]int)
-	for path := range stub.spec.Paths.Paths {
-		matcher := pathToRegexp(httpPath)
-		matches := matcher.FindAllString(path, -1)
-		scores[path] = len(matches)
+// overlayResponse uses the overlay, with any set and patch, as the body of
+// a status code that the spec doesn't define. JSON is served with a JSON
+// media type and other content is served raw, as plain text unless the
+// overlay has a Content-Type header.
+func (stub *stubState) overlayResponse(stubbed *StubbedResponse, request Request, operation *spec.Operation, responseOverlay Response) (*StubbedResponse, error) {
+	if !statusHasBody(stubbed.StatusCode) || !hasOverlayBody(responseOverlay) {
+		return stubbed, nil
+	}
+
+	if responseOverlay.Content != "" && !json.Valid([]byte(responseOverlay.Content)) {
+		stubbed.MediaType = "text/plain; charset=utf-8"
+		return stub.rawResponse(stubbed, request, operation, responseOverlay)
+	}
+
+	produces := []string{}
+	for _, mediaType := range operation.Produces {
+		if isJSONMediaType(mediaType) {
+			produces = append(produces, mediaType)
+		}
+	}
+	if len(produces) == 0 {
+		produces = []string{DefaultMediaType}
+	}
+	mediaType, err := NegotiateMediaType(request.Accept, produces)
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+	if err := ApplyResponseOverlay(responseOverlay, &data); err != nil {
+		return nil, errors.Wrapf(err, "overlay for %v response of operation %s", stubbed.StatusCode, operation.ID)
+	}
+
+	stubbed.MediaType = mediaType
+	stubbed.Body = data
+	return stubbed, nil
+}
+
+// hasOverlayBody reports whether the overlay provides any of the body
+func hasOverlayBody(response Response) bool {
+	return response.Content != "" || len(response.Set) != 0 || len(response.Patch) != 0
+}
+
+// rawResponse uses the overlay content or file as the body without
+// generating any data. The media type is the overlay's Content-Type
+// header or content type if it has one, otherwise it's negotiated as
+// usual unless it's already set.
+func (stub *stubState) rawResponse(stubbed *StubbedResponse, request Request, operation *spec.Operation, responseOverlay Response) (*StubbedResponse, error) {
+	body := responseOverlay.body
+	if body == nil {
+		body = []byte(responseOverlay.Content)
+	}
+	if !statusHasBody(stubbed.StatusCode) || len(body) == 0 {
+		return stubbed, nil
+	}
+
+	if responseOverlay.ContentType != "" {
+		stubbed.MediaType = responseOverlay.ContentType
+	}
+	for name, value := range stubbed.Headers {
+		if strings.EqualFold(name, "Content-Type") {
+			stubbed.MediaType = value
+		}
+	}
+	if stubbed.MediaType == "" {
+		mediaType, err := NegotiateMediaType(request.Accept, operation.Produces)
+		if err != nil {
+			return nil, err
+		}
+		stubbed.MediaType = mediaType
+	}
+
+	stubbed.Body = body
+	stubbed.Raw = true
+	return stubbed, nil
+}
+
+// HasBody reports whether a response should be sent with a body.
+// Responses without a schema and status codes that forbid a body
+// (1XX, 204 and 304) have no body.
+func HasBody(statusCode int, response spec.Response) bool {
+	return statusHasBody(statusCode) && response.Schema != nil
+}
+
+func statusHasBody(statusCode int) bool {
+	return statusCode >= 200 && statusCode != 204 && statusCode != 304
+}
+
+// Route is an HTTP request path and method that has been
+// matched to an operation in the spec
+type Route struct {
+	// Path is the spec path that matched i.e. /v1/pets/{petId}
+	Path      string
+	PathItem  spec.PathItem
+	Operation *spec.Operation
+	// Params are the path parameter values extracted from the request path
+	Params map[string]string
+}
+
+// FindOperation implements StubGenerator.FindOperation
+func (stub *stubState) FindOperation(httpPath string, httpMethod string) (*spec.Operation, error) {
+	route, err := stub.FindRoute(httpPath, httpMethod)
+	if err != nil {
+		return nil, err
+	}
+	return route.Operation, nil
+}
+
+// FindRoute implements StubGenerator.FindRoute
+func (stub *stubState) FindRoute(httpPath string, httpMethod string) (*Route, error) {
+	route, err := stub.FindPathItem(httpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// find the operation from the pathItem using http method
+	route.Operation = PathItemOperations(route.PathItem)[strings.ToUpper(httpMethod)]
+	if route.Operation == nil {
+		return nil, fmt.Errorf("no operation for HTTP %s %s", httpMethod, httpPath)
 	}
 
-	// pick the best matching path
-	// a lower score means less matching segments and a more specific path.
-	// we'll choose the most specific path
+	return route, nil
+}
+
+// FindPathItem implements StubGenerator.FindPathItem
+func (stub *stubState) FindPathItem(httpPath string) (*Route, error) {
+	// for every path that matches, calculate a score
+	// more path params means a higher score, 1 point per path param
 	var bestPath *string
-	for path, score := range scores {
-		if score != 0 && (bestPath == nil || score < scores[*bestPath]) {
+	var bestParams map[string]string
+	for path := range stub.spec.Paths.Paths {
+		matcher := pathToRegexp(path)
+		match := matcher.FindStringSubmatch(httpPath)
+		if match == nil {
+			continue
+		}
+
+		// pick the best matching path
+		// a lower score means less path params and a more specific path.
+		// we'll choose the most specific path
+		score := len(match) - 1
+		if bestPath == nil || score < len(bestParams) || (score == len(bestParams) && path < *bestPath) {
 			copy := string(path)
 			bestPath = &copy
+			bestParams = map[string]string{}
+			for i, name := range matcher.SubexpNames() {
+				if i != 0 {
+					bestParams[name] = match[i]
+				}
+			}
 		}
 	}
 
@@ -113,43 +443,39 @@ func (stub *StubGenerator) FindOperation(httpPath string, httpMethod string) (*s
 		return nil, fmt.Errorf("unknown path %s", httpPath)
 	}
 
-	// find the operation from the pathItem using http method
-	var operation *spec.Operation
-	switch strings.ToUpper(httpMethod) {
-	case "GET":
-		operation = stub.spec.Paths.Paths[*bestPath].Get
-	case "POST":
-		operation = stub.spec.Paths.Paths[*bestPath].Post
-	case "PUT":
-		operation = stub.spec.Paths.Paths[*bestPath].Put
-	case "PATCH":
-		operation = stub.spec.Paths.Paths[*bestPath].Patch
-	case "HEAD":
-		operation = stub.spec.Paths.Paths[*bestPath].Head
-	case "OPTIONS":
-		operation = stub.spec.Paths.Paths[*bestPath].Options
-	default:
-		operation = nil
-	}
-
-	if operation == nil {
-		return nil, fmt.Errorf("no operation for HTTP %s %s", httpMethod, httpPath)
-	}
-
-	return operation, nil
+	return &Route{
+		Path:     *bestPath,
+		PathItem: stub.spec.Paths.Paths[*bestPath],
+		Params:   bestParams,
+	}, nil
 }
 
 // pathToRegexp will convert an openapi path i.e. /api/{param}/thing/
-// into a regexp like /api/(.*)/thing/
+// into a regexp like /api/(?P<param>[^/]+)/thing/
 func pathToRegexp(path string) *regexp.Regexp {
 	quotedPath := regexp.QuoteMeta(path)
-	result := regexp.MustCompile(`(\\{\w+\\})`).ReplaceAllString(quotedPath, "(.*)")
+	result := regexp.MustCompile(`\\{(\w+)\\}`).ReplaceAllString(quotedPath, "(?P<$1>[^/]+)")
 	return regexp.MustCompile("^" + result + "$")
 }
 
-// FindResponse returns either the default response from an operation
-// or the response with the lowest HTTP status code (i.e. success codes over error codes)
-func (stub *StubGenerator) FindResponse(operation *spec.Operation) (*spec.Response, *int, error) {
+// FindResponse implements StubGenerator.FindResponse
+func (stub *stubState) FindResponse(operation *spec.Operation, preference ResponsePreference) (*spec.Response, *int, error) {
+	ranged := stub.ranged[operation]
+
+	if preference.StatusCode != 0 {
+		statusCode := preference.StatusCode
+		if response, ok := operation.Responses.StatusCodeResponses[statusCode]; ok {
+			return &response, &statusCode, nil
+		}
+		if response, ok := ranged[statusCode/100]; ok {
+			return &response, &statusCode, nil
+		}
+		if operation.Responses.Default != nil {
+			return operation.Responses.Default, &statusCode, nil
+		}
+		return nil, nil, errors.Wrapf(ErrNoPreferredResponse, "no response definition found for status code %v of operation %s", preference.StatusCode, operation.ID)
+	}
+
 	var response *spec.Response
 
 	lowestCode := 999
@@ -161,6 +487,21 @@ func (stub *StubGenerator) FindResponse(operation *spec.Operation) (*spec.Respon
 		}
 	}
 
+	// a range like 2XX is stubbed using the first code
+	// in the range i.e. 200. Exact codes win a tie.
+	for class, res := range ranged {
+		if class*100 < lowestCode {
+			tmp := res
+			response = &tmp
+			lowestCode = class * 100
+		}
+	}
+
+	if response == nil && operation.Responses.Default != nil {
+		response = operation.Responses.Default
+		lowestCode = DefaultStatusCode
+	}
+
 	if response == nil {
 		return nil, nil, fmt.Errorf("no response definition found for operation %s", operation.ID)
 	}
@@ -224,3 +565,39 @@ func ExpandOperationIDs(document *loads.Document) {
 		}
 	}
 }
+
+// ExpandMediaTypes copies the spec level consumes and produces
+// onto every operation that doesn't declare it's own, so that
+// operations can be inspected without looking at the whole spec.
+func ExpandMediaTypes(document *loads.Document) {
+	for _, pathItem := range document.Spec().Paths.Paths {
+		for _, op := range PathItemOperations(pathItem) {
+			if len(op.Consumes) == 0 {
+				op.Consumes = document.Spec().Consumes
+			}
+			if len(op.Produces) == 0 {
+				op.Produces = document.Spec().Produces
+			}
+		}
+	}
+}
+
+// PathItemOperations returns the operations defined on a path item
+// keyed by their upper case HTTP method
+func PathItemOperations(pathItem spec.PathItem) map[string]*spec.Operation {
+	operations := map[string]*spec.Operation{}
+	for method, op := range map[string]*spec.Operation{
+		"GET":     pathItem.Get,
+		"PUT":     pathItem.Put,
+		"POST":    pathItem.Post,
+		"PATCH":   pathItem.Patch,
+		"DELETE":  pathItem.Delete,
+		"HEAD":    pathItem.Head,
+		"OPTIONS": pathItem.Options,
+	} {
+		if op != nil {
+			operations[method] = op
+		}
+	}
+	return operations
+}
@@ -2,8 +2,11 @@ package generator
 
 import (
 	"testing"
+	"time"
 
 	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+	"github.com/pkg/errors"
 
 	"github.com/stretchr/testify/require"
 )
@@ -51,3 +54,153 @@ func TestExpandOperationIDs(t *testing.T) {
 
 	require.Equal(document.Spec().Paths.Paths["/pets/{petId}"].Get.ID, "Get: /pets/{petId}")
 }
+
+func TestFindOperationWithPathParams(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{})
+	require.NoError(err)
+
+	operation, err := stub.FindOperation("/v1/pets/123", "GET")
+	require.NoError(err)
+	require.Equal("Get: /v1/pets/{petId}", operation.ID)
+
+	operation, err = stub.FindOperation("/v1/pets", "GET")
+	require.NoError(err)
+	require.Equal("listPets", operation.ID)
+
+	_, err = stub.FindOperation("/v1/pets/123/toys", "GET")
+	require.Error(err)
+}
+
+func TestFindOperationDelete(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{})
+	require.NoError(err)
+	stub.current().spec.Paths.Paths["/v1/pets/{petId}"] = spec.PathItem{PathItemProps: spec.PathItemProps{
+		Delete: &spec.Operation{OperationProps: spec.OperationProps{ID: "deletePet"}},
+	}}
+
+	operation, err := stub.FindOperation("/v1/pets/123", "DELETE")
+	require.NoError(err)
+	require.Equal("deletePet", operation.ID)
+}
+
+func TestFindRouteWithPathParams(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{})
+	require.NoError(err)
+
+	route, err := stub.FindRoute("/v1/pets/123", "GET")
+	require.NoError(err)
+
+	require.Equal("/v1/pets/{petId}", route.Path)
+	require.Equal(map[string]string{"petId": "123"}, route.Params)
+}
+
+func TestFindResponseWithPreference(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{})
+	require.NoError(err)
+
+	operation, err := stub.FindOperation("/v1/pets", "POST")
+	require.NoError(err)
+
+	_, statusCode, err := stub.FindResponse(operation, ResponsePreference{StatusCode: 201})
+	require.NoError(err)
+	require.Equal(201, *statusCode)
+
+	// codes that aren't defined fall back to the default response
+	response, statusCode, err := stub.FindResponse(operation, ResponsePreference{StatusCode: 418})
+	require.NoError(err)
+	require.Equal(418, *statusCode)
+	require.Equal(operation.Responses.Default, response)
+}
+
+func TestFindResponseWithDefault(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("./testdata/responses.yaml", StubGeneratorOptions{})
+	require.NoError(err)
+
+	operation, err := stub.FindOperation("/default", "GET")
+	require.NoError(err)
+
+	_, statusCode, err := stub.FindResponse(operation, ResponsePreference{})
+	require.NoError(err)
+	require.Equal(DefaultStatusCode, *statusCode)
+}
+
+func TestFindResponseWithRange(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("./testdata/responses.yaml", StubGeneratorOptions{})
+	require.NoError(err)
+
+	operation, err := stub.FindOperation("/ranged", "GET")
+	require.NoError(err)
+
+	response, statusCode, err := stub.FindResponse(operation, ResponsePreference{})
+	require.NoError(err)
+	require.Equal(200, *statusCode)
+	require.Contains(response.Schema.Properties, "message")
+
+	_, statusCode, err = stub.FindResponse(operation, ResponsePreference{StatusCode: 204})
+	require.NoError(err)
+	require.Equal(204, *statusCode)
+
+	_, _, err = stub.FindResponse(operation, ResponsePreference{StatusCode: 500})
+	require.Equal(ErrNoPreferredResponse, errors.Cause(err))
+}
+
+func TestStubResponseWithForcedStatus(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{Overlay: "testdata/status.yaml"})
+	require.NoError(err)
+
+	response, err := stub.StubResponse(Request{Path: "/v1/pets", Method: "GET"})
+	require.NoError(err)
+	require.Equal(500, response.StatusCode)
+	require.Equal(float64(42), response.Body.(map[string]interface{})["code"])
+	require.Contains(response.Body, "message")
+	require.Equal(&Latency{Mode: "fixed", Min: 10 * time.Millisecond}, response.Delay)
+}
+
+func TestStubResponseWithRawOverlay(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{Overlay: "testdata/status.yaml"})
+	require.NoError(err)
+
+	response, err := stub.StubResponse(Request{Path: "/v1/pets", Method: "POST"})
+	require.NoError(err)
+	require.Equal(503, response.StatusCode)
+	require.True(response.Raw)
+	require.Equal("text/plain", response.MediaType)
+	require.Equal([]byte("try later"), response.Body)
+}
+
+func TestStubResponseWithForcedStatusNotInSpec(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("testdata/responses.yaml", StubGeneratorOptions{Overlay: "testdata/undefined-status.yaml"})
+	require.NoError(err)
+
+	response, err := stub.StubResponse(Request{Path: "/ranged", Method: "GET"})
+	require.NoError(err)
+	require.Equal(503, response.StatusCode)
+	require.Equal("application/json", response.MediaType)
+	require.False(response.Raw)
+	require.Equal(map[string]interface{}{"retry": true, "after": 5}, response.Body)
+
+	response, err = stub.StubResponse(Request{Path: "/broken", Method: "GET"})
+	require.NoError(err)
+	require.Equal(503, response.StatusCode)
+	require.True(response.Raw)
+	require.Equal("text/plain; charset=utf-8", response.MediaType)
+	require.Equal([]byte("try again later"), response.Body)
+}
@@ -4,16 +4,37 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"reflect"
-
-	"github.com/imdario/mergo"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 	yaml "gopkg.in/yaml.v2"
 )
 
+// Overlay paths can be concrete (/users/1), spec style templates
+// (/users/{id}), globs (/users/*) or regular expressions (^/users/\d+$).
+// When more than one path matches a request that order is their precedence.
 type Overlay struct {
-	Paths map[string]PathItem `yaml:"paths"`
+	// Include are overlay files or directories, relative to
+	// this file, that are merged before this file
+	Include []string            `yaml:"include"`
+	Paths   map[string]PathItem `yaml:"paths"`
+
+	// sources are the files that each response came from
+	// keyed by method, path and status code
+	sources map[string]string
+	// conflicts are the responses that replaced
+	// a response from another file
+	conflicts []error
+	// files are the overlay files, directories and
+	// response files that the overlay was loaded from
+	files []string
+
+	// matchers are the compiled paths in order of precedence
+	matchers []pathMatcher
 }
 
 type PathItem struct {
@@ -21,21 +42,170 @@ type PathItem struct {
 	Put     *Operation `yaml:"put,omitempty"`
 	Post    *Operation `yaml:"post,omitempty"`
 	Patch   *Operation `yaml:"patch,omitempty"`
+	Delete  *Operation `yaml:"delete,omitempty"`
 	Options *Operation `yaml:"options,omitempty"`
 	Head    *Operation `yaml:"head,omitempty"`
 }
 
 type Operation struct {
-	Responses map[int]Response `yaml:"responses"`
+	Latency   string            `yaml:"latency"`
+	Responses map[int]Responses `yaml:"responses"`
 }
 
 type Response struct {
+	// Content is merged into the generated data as a JSON
+	// merge patch where null removes a field
 	Content string `yaml:"content"`
+	// Set assigns values to JSON Pointers in the generated data
+	Set map[string]interface{} `yaml:"set"`
+	// Patch is a list of JSON Patch operations that are
+	// applied to the generated data
+	Patch []PatchOperation `yaml:"patch"`
+	// Headers are set on the response. A null value
+	// removes a header that would otherwise be stubbed.
+	Headers map[string]*string `yaml:"headers"`
+	// Status forces the status code of the response. The spec's
+	// response for that code (if any) is used for the rest of it.
+	Status int `yaml:"status"`
+	// Delay adds latency to this response on top of
+	// any latency of the operation
+	Delay string `yaml:"delay"`
+	// Raw serves the content as the body as is
+	// instead of merging it into generated data
+	Raw bool `yaml:"raw"`
+	// File is read for the content, relative to the overlay file.
+	// JSON files are used like content and other files are served raw.
+	File string `yaml:"file"`
+	// ContentType of a raw body. By default it's inferred from
+	// the file extension or the content-type header.
+	ContentType string `yaml:"contentType"`
+	// Sequence serves a different response each time the
+	// overlay is used. The other fields are ignored.
+	Sequence *Sequence `yaml:"sequence"`
+
+	// body is the content of a raw file
+	body []byte
+	// Match restricts the response to requests that meet
+	// it's conditions. nil matches every request.
+	Match *Match `yaml:"match"`
+	// Priority orders the responses for a status code.
+	// Higher priorities are tried first.
+	Priority int `yaml:"priority"`
 }
 
-// LoadOverlayFile reads an overlay.yaml file into an Overlay struct
+// Responses are the overlays for a single status code. In yaml
+// it's either a single response or a list of responses.
+type Responses []Response
+
+// UnmarshalYAML reads a single response or a list of responses
+func (responses *Responses) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []Response
+	if err := unmarshal(&list); err == nil {
+		*responses = list
+		return nil
+	}
+
+	var single Response
+	if err := unmarshal(&single); err != nil {
+		return err
+	}
+	*responses = Responses{single}
+	return nil
+}
+
+// Find returns the highest priority response that matches the
+// request or nil if none of them do. Responses with the same
+// priority are tried in order.
+func (responses Responses) Find(request Request) (*Response, error) {
+	ordered := make(Responses, len(responses))
+	copy(ordered, responses)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+
+	for _, response := range ordered {
+		ok, err := response.Match.Matches(request)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return &response, nil
+		}
+	}
+	return nil, nil
+}
+
+// operations returns the operation overlays of the
+// path item keyed by their upper case HTTP method
+func (pathItem PathItem) operations() map[string]*Operation {
+	operations := map[string]*Operation{}
+	for method, operation := range map[string]*Operation{
+		"GET":     pathItem.Get,
+		"PUT":     pathItem.Put,
+		"POST":    pathItem.Post,
+		"PATCH":   pathItem.Patch,
+		"DELETE":  pathItem.Delete,
+		"OPTIONS": pathItem.Options,
+		"HEAD":    pathItem.Head,
+	} {
+		if operation != nil {
+			operations[method] = operation
+		}
+	}
+	return operations
+}
+
+// load checks the parts of a response that can't be checked
+// by unmarshalling it and reads it's files from the directory
+func (response *Response) load(dir string) error {
+	if _, err := ParseLatency(response.Delay); err != nil {
+		return err
+	}
+	if err := response.loadFile(dir); err != nil {
+		return err
+	}
+	if response.Sequence != nil {
+		if err := response.Sequence.validate(); err != nil {
+			return err
+		}
+		for i := range response.Sequence.Responses {
+			if err := response.Sequence.Responses[i].Response.load(dir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadOverlayFile reads an overlay.yaml file into an Overlay struct.
+// Included files are merged first so that the file overrides them.
 func LoadOverlayFile(path string) (*Overlay, error) {
-	content, err := ioutil.ReadFile(path)
+	overlay, err := loadOverlayFile(path, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := overlay.compile(); err != nil {
+		return nil, err
+	}
+
+	return overlay, nil
+}
+
+// loadOverlayFile reads an overlay file and it's includes. loading
+// holds the files that are being loaded so include cycles are found.
+func loadOverlayFile(file string, loading map[string]bool) (*Overlay, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading overlay file")
+	}
+	if loading[abs] {
+		return nil, fmt.Errorf("overlay file %v includes itself", file)
+	}
+	loading[abs] = true
+	defer delete(loading, abs)
+
+	content, err := ioutil.ReadFile(file)
 	if err != nil {
 		return nil, errors.Wrap(err, "reading overlay file")
 	}
@@ -43,10 +213,50 @@ func LoadOverlayFile(path string) (*Overlay, error) {
 	overlay := &Overlay{}
 	err = yaml.Unmarshal(content, overlay)
 	if err != nil {
-		return nil, errors.Wrap(err, "unmarshalling overlay file")
+		return nil, errors.Wrapf(err, "unmarshalling overlay file %v", file)
 	}
 
-	return overlay, nil
+	dir := filepath.Dir(file)
+	for path, pathItem := range overlay.Paths {
+		for method, operation := range pathItem.operations() {
+			if _, err := ParseLatency(operation.Latency); err != nil {
+				return nil, errors.Wrapf(err, "overlay for %v %v", method, path)
+			}
+			for statusCode, responses := range operation.Responses {
+				for i := range responses {
+					if err := responses[i].load(dir); err != nil {
+						return nil, errors.Wrapf(err, "overlay for %v response of %v %v", statusCode, method, path)
+					}
+				}
+			}
+		}
+	}
+	overlay.setSource(file)
+	overlay.files = append([]string{file}, overlay.responseFiles()...)
+
+	includes := []string{}
+	for _, include := range overlay.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(dir, include)
+		}
+		includes = append(includes, include)
+	}
+	files, dirs, err := expandOverlayPaths(includes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "including overlays in %v", file)
+	}
+
+	merged := &Overlay{files: dirs}
+	for _, include := range files {
+		included, err := loadOverlayFile(include, loading)
+		if err != nil {
+			return nil, err
+		}
+		merged.merge(included)
+	}
+	merged.merge(overlay)
+
+	return merged, nil
 }
 
 // EmptyOverlay is used when the user doesn't provide
@@ -55,64 +265,205 @@ func EmptyOverlay() Overlay {
 	return Overlay{}
 }
 
-func (overlay *Overlay) FindResponse(path string, method string, statusCode int) (*Response, error) {
-	for pathOverlay, pathItem := range overlay.Paths {
-		if pathOverlay == path {
-			var operationOverlay *Operation
-			switch method {
-			case "GET":
-				operationOverlay = pathItem.Get
-			case "POST":
-				operationOverlay = pathItem.Post
-			case "PUT":
-				operationOverlay = pathItem.Put
-			case "PATCH":
-				operationOverlay = pathItem.Patch
-			case "OPTIONS":
-				operationOverlay = pathItem.Options
-			case "HEAD":
-				operationOverlay = pathItem.Head
+// FindOperation returns the operation overlay for a path
+// and method or nil if there isn't one
+func (overlay *Overlay) FindOperation(path string, method string) *Operation {
+	operation, _ := overlay.findOperation(path, method)
+	return operation
+}
+
+// findOperation returns the operation overlay of the highest precedence
+// path that matches the request path and has an overlay for the method,
+// along with any variables extracted from the path
+func (overlay *Overlay) findOperation(httpPath string, method string) (*Operation, map[string]string) {
+	if pathItem, ok := overlay.Paths[httpPath]; ok {
+		if operation := pathItem.operations()[strings.ToUpper(method)]; operation != nil {
+			return operation, map[string]string{}
+		}
+	}
+
+	matchers := overlay.matchers
+	if matchers == nil && len(overlay.Paths) != 0 {
+		// overlays that weren't loaded from a file
+		// haven't been compiled
+		compiled := Overlay{Paths: overlay.Paths}
+		if err := compiled.compile(); err != nil {
+			return nil, nil
+		}
+		matchers = compiled.matchers
+	}
+
+	for _, matcher := range matchers {
+		match := matcher.regexp.FindStringSubmatch(httpPath)
+		if match == nil {
+			continue
+		}
+		operation := overlay.Paths[matcher.path].operations()[strings.ToUpper(method)]
+		if operation == nil {
+			continue
+		}
+		vars := map[string]string{}
+		for i, name := range matcher.regexp.SubexpNames() {
+			if i != 0 && name != "" {
+				vars[name] = match[i]
 			}
-			if operationOverlay != nil {
-				if response, ok := operationOverlay.Responses[statusCode]; ok {
-					return &response, nil
-				}
+		}
+		return operation, vars
+	}
+
+	return nil, nil
+}
+
+// kinds of overlay path in order of precedence
+const (
+	templatePath = iota
+	globPath
+	regexpPath
+)
+
+type pathMatcher struct {
+	path   string
+	kind   int
+	regexp *regexp.Regexp
+	// specificity orders paths of the same kind.
+	// Lower is more specific.
+	specificity int
+}
+
+// compile converts the overlay paths that aren't concrete into
+// regular expressions and sorts them by precedence
+func (overlay *Overlay) compile() error {
+	matchers := []pathMatcher{}
+	for path := range overlay.Paths {
+		switch {
+		case strings.HasPrefix(path, "^"):
+			expression, err := regexp.Compile(path)
+			if err != nil {
+				return errors.Wrapf(err, "compiling overlay path %v", path)
 			}
+			matchers = append(matchers, pathMatcher{path: path, kind: regexpPath, regexp: expression})
+
+		case strings.Contains(path, "*"):
+			expression := globToRegexp(path)
+			literals := len(strings.Replace(path, "*", "", -1))
+			matchers = append(matchers, pathMatcher{path: path, kind: globPath, regexp: expression, specificity: -literals})
+
+		case strings.Contains(path, "{"):
+			expression := pathToRegexp(path)
+			matchers = append(matchers, pathMatcher{path: path, kind: templatePath, regexp: expression, specificity: expression.NumSubexp()})
+		}
+	}
+
+	sort.Slice(matchers, func(i, j int) bool {
+		a, b := matchers[i], matchers[j]
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		if a.specificity != b.specificity {
+			return a.specificity < b.specificity
+		}
+		return a.path < b.path
+	})
+
+	overlay.matchers = matchers
+	return nil
+}
+
+// globToRegexp converts a glob path where * matches within a
+// path segment and ** matches across segments into a regexp
+func globToRegexp(glob string) *regexp.Regexp {
+	parts := strings.Split(glob, "**")
+	for i, part := range parts {
+		segments := strings.Split(part, "*")
+		for j, segment := range segments {
+			segments[j] = regexp.QuoteMeta(segment)
+		}
+		parts[i] = strings.Join(segments, "[^/]*")
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// ErrNoOverlay is returned by FindResponse when no
+// response overlay matches the request
+var ErrNoOverlay = errors.New("no response overlay")
+
+// FindResponse returns the response overlay for the request's path
+// and method that matches the request and has the given status code
+func (overlay *Overlay) FindResponse(request Request, statusCode int) (*Response, error) {
+	if operationOverlay := overlay.FindOperation(request.Path, request.Method); operationOverlay != nil {
+		response, err := operationOverlay.Responses[statusCode].Find(request)
+		if err != nil {
+			return nil, errors.Wrapf(err, "matching %v %v %v response overlays", statusCode, request.Method, request.Path)
+		}
+		if response != nil {
+			return response, nil
 		}
 	}
-	return nil, fmt.Errorf("response overlay for %v %v %v not found", statusCode, method, path)
+	return nil, errors.Wrapf(ErrNoOverlay, "response overlay for %v %v %v not found", statusCode, request.Method, request.Path)
 }
 
 // ApplyResponseOverlay expects data to be passed by reference.
-// The response overlay will be applied by merging/overriding data.
+// The content is applied to data as an RFC 7386 merge patch, so objects
+// are merged and null removes a field. Then the set assignments and
+// patch operations are applied in that order. Content that isn't
+// JSON replaces string data.
 func ApplyResponseOverlay(response Response, data interface{}) error {
-	switch reflect.Indirect(reflect.ValueOf(data)).Kind() {
-	case reflect.String:
-		*data.(*string) = string(response.Content)
-		return nil
+	target := reflect.ValueOf(data)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return fmt.Errorf("response overlay data must be passed by reference")
+	}
+	target = target.Elem()
 
-	case reflect.Map:
-		var override map[string]interface{}
-		err := json.Unmarshal([]byte(response.Content), &override)
-		if err != nil {
-			return errors.Wrap(err, "unmarshalling object response overlay")
+	var value interface{}
+	if target.IsValid() {
+		value = target.Interface()
+	}
+
+	if response.Content != "" {
+		var override interface{}
+		if err := json.Unmarshal([]byte(response.Content), &override); err != nil {
+			if _, ok := value.(string); !ok {
+				return errors.Wrap(err, "unmarshalling response overlay")
+			}
+			override = response.Content
+		}
+		value = MergePatch(value, override)
+	}
+
+	pointers := make([]string, 0, len(response.Set))
+	for pointer := range response.Set {
+		pointers = append(pointers, pointer)
+	}
+	// shorter pointers first so that parents are set before their children
+	sort.Slice(pointers, func(i, j int) bool {
+		if len(pointers[i]) != len(pointers[j]) {
+			return len(pointers[i]) < len(pointers[j])
 		}
-		err = mergo.Merge(data, override, mergo.WithOverride)
+		return pointers[i] < pointers[j]
+	})
+	for _, pointer := range pointers {
+		var err error
+		value, err = SetPointer(value, pointer, response.Set[pointer])
 		if err != nil {
-			return errors.Wrap(err, "merging response overlay with generated response stub")
+			return errors.Wrapf(err, "setting %v", pointer)
 		}
-		return nil
+	}
 
-	default:
-		// I don't know why, but I can't match a reflect.Slice
-		// so instead i'm handling slices in the default case
-		// TODO: actually solve my problems...
-		var override interface{}
-		err := json.Unmarshal([]byte(response.Content), &override)
+	if len(response.Patch) != 0 {
+		var err error
+		value, err = ApplyPatch(value, response.Patch)
 		if err != nil {
-			return errors.Wrap(err, "unmarshalling array response overlay")
+			return errors.Wrap(err, "applying response overlay patch")
 		}
-		*data.(*interface{}) = override
+	}
+
+	if value == nil {
+		target.Set(reflect.Zero(target.Type()))
 		return nil
 	}
+	if !reflect.TypeOf(value).AssignableTo(target.Type()) {
+		return fmt.Errorf("response overlay is a %T but the stubbed data is a %v", value, target.Type())
+	}
+	target.Set(reflect.ValueOf(value))
+	return nil
 }
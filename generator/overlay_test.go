@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlayPathPrecedence(t *testing.T) {
+	require := require.New(t)
+
+	overlay, err := LoadOverlayFile("testdata/paths.yaml")
+	require.NoError(err)
+
+	find := func(path string) (string, map[string]string) {
+		operation, vars := overlay.findOperation(path, "GET")
+		if operation == nil {
+			return "", nil
+		}
+		return operation.Latency, vars
+	}
+
+	latency, vars := find("/users/1")
+	require.Equal("1ms", latency)
+	require.Empty(vars)
+
+	latency, vars = find("/users/2")
+	require.Equal("2ms", latency)
+	require.Equal(map[string]string{"id": "2"}, vars)
+
+	latency, _ = find("/users/2/posts")
+	require.Equal("3ms", latency)
+
+	latency, _ = find("/files/a/b/c.txt")
+	require.Equal("5ms", latency)
+
+	latency, vars = find("/users/2/posts/3")
+	require.Equal("6ms", latency)
+	require.Equal(map[string]string{"id": "2", "post": "3"}, vars)
+
+	latency, _ = find("/users/2/posts/3/comments")
+	require.Equal("", latency)
+
+	require.Nil(overlay.FindOperation("/users/2", "POST"))
+}
+
+func TestOverlayGlobWithinSegment(t *testing.T) {
+	require := require.New(t)
+
+	overlay, err := LoadOverlayFile("testdata/paths.yaml")
+	require.NoError(err)
+
+	require.Equal("7ms", overlay.FindOperation("/images/a/thumb", "GET").Latency)
+	require.Nil(overlay.FindOperation("/images/a/b/thumb", "GET"))
+	require.Nil(overlay.FindOperation("/users/2/3/posts", "GET"))
+}
+
+func TestOverlayInvalidRegexp(t *testing.T) {
+	require := require.New(t)
+
+	_, err := LoadOverlayFile("testdata/invalid-path.yaml")
+	require.Error(err)
+}
+
+func TestOverlayDeleteOperation(t *testing.T) {
+	require := require.New(t)
+
+	overlay, err := LoadOverlayFile("testdata/paths.yaml")
+	require.NoError(err)
+
+	operation, vars := overlay.findOperation("/users/2", "DELETE")
+	require.NotNil(operation)
+	require.Equal("12ms", operation.Latency)
+	require.Equal(map[string]string{"id": "2"}, vars)
+
+	require.Equal("15ms", overlay.FindOperation("/files/a", "delete").Latency)
+	require.Nil(overlay.FindOperation("/users/2/posts/3", "DELETE"))
+}
+
+func TestStubResponseWithOverlayPathVariables(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{Overlay: "testdata/path-variables.yaml"})
+	require.NoError(err)
+
+	response, err := stub.StubResponse(Request{Path: "/v1/pets/7", Method: "GET"})
+	require.NoError(err)
+	pet := response.Body.([]interface{})[0].(map[string]interface{})
+	require.Equal("pet 7", pet["name"])
+	require.Equal("7", pet["tag"])
+}
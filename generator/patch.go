@@ -0,0 +1,300 @@
+package generator
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/go-openapi/jsonpointer"
+	"github.com/pkg/errors"
+)
+
+// PatchOperation is an RFC 6902 JSON Patch operation
+type PatchOperation struct {
+	// Op is one of add, remove, replace, move, copy or test
+	Op    string      `yaml:"op"`
+	Path  string      `yaml:"path"`
+	From  string      `yaml:"from"`
+	Value interface{} `yaml:"value"`
+}
+
+// MergePatch applies an RFC 7386 JSON merge patch to the target.
+// Objects are merged recursively, null values remove fields and
+// anything else replaces the target.
+func MergePatch(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = MergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// ApplyPatch applies RFC 6902 JSON Patch operations to the
+// document and returns the patched document
+func ApplyPatch(document interface{}, operations []PatchOperation) (interface{}, error) {
+	var err error
+	for i, operation := range operations {
+		document, err = applyPatchOperation(document, operation)
+		if err != nil {
+			return nil, errors.Wrapf(err, "patch operation %v (%v %v)", i, operation.Op, operation.Path)
+		}
+	}
+	return document, nil
+}
+
+func applyPatchOperation(document interface{}, operation PatchOperation) (interface{}, error) {
+	value := normalizeYAML(operation.Value)
+
+	switch operation.Op {
+	case "add":
+		return pointerAdd(document, operation.Path, value)
+
+	case "remove":
+		document, _, err := pointerRemove(document, operation.Path)
+		return document, err
+
+	case "replace":
+		if _, err := pointerGet(document, operation.Path); err != nil {
+			return nil, err
+		}
+		return pointerSet(document, operation.Path, value)
+
+	case "move":
+		document, moved, err := pointerRemove(document, operation.From)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(document, operation.Path, moved)
+
+	case "copy":
+		copied, err := pointerGet(document, operation.From)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(document, operation.Path, normalizeJSON(copied))
+
+	case "test":
+		actual, err := pointerGet(document, operation.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(normalizeJSON(actual), normalizeJSON(value)) {
+			return nil, fmt.Errorf("test failed: %v is %v", operation.Path, matchText(actual))
+		}
+		return document, nil
+
+	default:
+		return nil, fmt.Errorf("unknown patch operation %q", operation.Op)
+	}
+}
+
+// SetPointer assigns a value to the location of a JSON Pointer
+// in the document, creating missing objects along the way, and
+// returns the updated document
+func SetPointer(document interface{}, pointer string, value interface{}) (interface{}, error) {
+	return pointerSet(document, pointer, normalizeYAML(value))
+}
+
+func pointerTokens(pointer string) ([]string, error) {
+	parsed, err := jsonpointer.New(pointer)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.DecodedTokens(), nil
+}
+
+func pointerGet(document interface{}, pointer string) (interface{}, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+	value := document
+	for _, token := range tokens {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%v not found", pointer)
+			}
+			value = child
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%v not found", pointer)
+			}
+			value = node[index]
+		default:
+			return nil, fmt.Errorf("%v not found", pointer)
+		}
+	}
+	return value, nil
+}
+
+// pointerAdd follows RFC 6902 add: object members are set
+// and array items are inserted ("-" appends)
+func pointerAdd(document interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return updatePointer(document, tokens, false, func(parent interface{}, token string) (interface{}, error) {
+		switch parent := parent.(type) {
+		case map[string]interface{}:
+			parent[token] = value
+			return parent, nil
+		case []interface{}:
+			index := len(parent)
+			if token != "-" {
+				if index, err = arrayIndex(token, len(parent)); err != nil {
+					return nil, err
+				}
+			}
+			parent = append(parent, nil)
+			copy(parent[index+1:], parent[index:])
+			parent[index] = value
+			return parent, nil
+		default:
+			return nil, fmt.Errorf("can't add %v to a %T", token, parent)
+		}
+	})
+}
+
+// pointerSet replaces the value at the pointer, creating
+// missing objects along the way
+func pointerSet(document interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return updatePointer(document, tokens, true, func(parent interface{}, token string) (interface{}, error) {
+		switch parent := parent.(type) {
+		case map[string]interface{}:
+			parent[token] = value
+			return parent, nil
+		case []interface{}:
+			if token == "-" {
+				return append(parent, value), nil
+			}
+			index, err := arrayIndex(token, len(parent)-1)
+			if err != nil {
+				return nil, err
+			}
+			parent[index] = value
+			return parent, nil
+		default:
+			return nil, fmt.Errorf("can't set %v on a %T", token, parent)
+		}
+	})
+}
+
+// pointerRemove removes the value at the pointer and returns it
+func pointerRemove(document interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, document, nil
+	}
+
+	var removed interface{}
+	document, err = updatePointer(document, tokens, false, func(parent interface{}, token string) (interface{}, error) {
+		switch parent := parent.(type) {
+		case map[string]interface{}:
+			value, ok := parent[token]
+			if !ok {
+				return nil, fmt.Errorf("%v not found", token)
+			}
+			removed = value
+			delete(parent, token)
+			return parent, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(parent)-1)
+			if err != nil {
+				return nil, err
+			}
+			removed = parent[index]
+			return append(parent[:index], parent[index+1:]...), nil
+		default:
+			return nil, fmt.Errorf("can't remove %v from a %T", token, parent)
+		}
+	})
+	return document, removed, err
+}
+
+// updatePointer walks to the parent of the last token and replaces
+// it with the result of update. Missing objects are created when create is set.
+func updatePointer(document interface{}, tokens []string, create bool, update func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		if document == nil && create {
+			document = map[string]interface{}{}
+		}
+		return update(document, tokens[0])
+	}
+
+	token := tokens[0]
+	switch node := document.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			if !create {
+				return nil, fmt.Errorf("%v not found", token)
+			}
+			child = map[string]interface{}{}
+		}
+		child, err := updatePointer(child, tokens[1:], create, update)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+
+	case []interface{}:
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := updatePointer(node[index], tokens[1:], create, update)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = child
+		return node, nil
+
+	case nil:
+		if !create {
+			return nil, fmt.Errorf("%v not found", token)
+		}
+		return updatePointer(map[string]interface{}{}, tokens, create, update)
+
+	default:
+		return nil, fmt.Errorf("can't follow %v into a %T", token, document)
+	}
+}
+
+// arrayIndex parses an array index token that must be within [0, max]
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return index, nil
+}
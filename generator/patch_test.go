@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func parseJSON(t *testing.T, content string) interface{} {
+	var value interface{}
+	require.NoError(t, json.Unmarshal([]byte(content), &value))
+	return value
+}
+
+func TestMergePatch(t *testing.T) {
+	require := require.New(t)
+
+	target := parseJSON(t, `{"a": "b", "c": {"d": "e", "f": "g"}}`)
+	patch := parseJSON(t, `{"a": "z", "c": {"f": null}}`)
+
+	require.Equal(parseJSON(t, `{"a": "z", "c": {"d": "e"}}`), MergePatch(target, patch))
+	require.Equal(parseJSON(t, `[1]`), MergePatch(target, parseJSON(t, `[1]`)))
+}
+
+func TestApplyPatch(t *testing.T) {
+	require := require.New(t)
+
+	document := parseJSON(t, `{"pets": [{"name": "a"}, {"name": "b"}, {"name": "c"}], "total": 3}`)
+
+	operations := []PatchOperation{}
+	require.NoError(yaml.Unmarshal([]byte(`
+- op: replace
+  path: /pets/2/name
+  value: rex
+- op: add
+  path: /pets/-
+  value: {name: d}
+- op: remove
+  path: /total
+- op: copy
+  from: /pets/0
+  path: /first
+- op: move
+  from: /pets/1
+  path: /second
+- op: test
+  path: /first/name
+  value: a
+`), &operations))
+
+	patched, err := ApplyPatch(document, operations)
+	require.NoError(err)
+	require.Equal(parseJSON(t, `{
+		"pets": [{"name": "a"}, {"name": "rex"}, {"name": "d"}],
+		"first": {"name": "a"},
+		"second": {"name": "b"}
+	}`), patched)
+
+	_, err = ApplyPatch(patched, []PatchOperation{{Op: "test", Path: "/first/name", Value: "b"}})
+	require.Error(err)
+
+	_, err = ApplyPatch(patched, []PatchOperation{{Op: "replace", Path: "/missing", Value: 1}})
+	require.Error(err)
+}
+
+func TestApplyResponseOverlayWithSetAndNull(t *testing.T) {
+	require := require.New(t)
+
+	data := parseJSON(t, `{"id": 1, "name": "a", "tags": ["x", "y", "z"]}`)
+	overlay := Response{
+		Content: `{"name": null}`,
+		Set: map[string]interface{}{
+			"/tags/2":     "changed",
+			"/owner/name": "sam",
+		},
+	}
+
+	require.NoError(ApplyResponseOverlay(overlay, &data))
+	require.Equal(parseJSON(t, `{"id": 1, "tags": ["x", "y", "changed"], "owner": {"name": "sam"}}`), data)
+}
@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-openapi/spec"
+)
+
+// Preflight stubs every response body and header in the spec once
+// so that schemas which can't be stubbed are found at startup
+// rather than by the first request that uses them. Operation
+// extensions are also checked.
+// An error is returned for each response that failed.
+func (stub *StubGenerator) Preflight() []error {
+	return stub.current().Preflight()
+}
+
+// Preflight implements StubGenerator.Preflight
+func (stub *stubState) Preflight() []error {
+	paths := make([]string, 0, len(stub.spec.Paths.Paths))
+	for path := range stub.spec.Paths.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	errs := []error{}
+	for _, path := range paths {
+		for method, operation := range PathItemOperations(stub.spec.Paths.Paths[path]) {
+			if value, ok := operation.Extensions.GetString(LatencyExtension); ok {
+				if _, err := ParseLatency(value); err != nil {
+					errs = append(errs, fmt.Errorf("operation %v (%v %v) %v: %v", operation.ID, method, path, LatencyExtension, err))
+				}
+			}
+
+			responses := map[string]*specResponse{}
+			for code, response := range operation.Responses.StatusCodeResponses {
+				response := response
+				responses[fmt.Sprint(code)] = &specResponse{code, &response}
+			}
+			for class, response := range stub.ranged[operation] {
+				response := response
+				responses[fmt.Sprintf("%dXX", class)] = &specResponse{class * 100, &response}
+			}
+			if operation.Responses.Default != nil {
+				responses["default"] = &specResponse{DefaultStatusCode, operation.Responses.Default}
+			}
+
+			for name, response := range responses {
+				for header, definition := range response.Headers {
+					if err := recoverSchemaError(func() { StubHeader(definition) }); err != nil {
+						errs = append(errs, fmt.Errorf("operation %v (%v %v) %v response header %v: %v", operation.ID, method, path, name, header, err))
+					}
+				}
+				if !HasBody(response.statusCode, *response.Response) {
+					continue
+				}
+				if err := recoverSchemaError(func() { StubSchema(*response.Schema) }); err != nil {
+					errs = append(errs, fmt.Errorf("operation %v (%v %v) %v response: %v", operation.ID, method, path, name, err))
+				}
+			}
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+
+	return errs
+}
+
+type specResponse struct {
+	statusCode int
+	*spec.Response
+}
+
+// recoverSchemaError calls fn and returns the
+// value it panicked with as an error
+func recoverSchemaError(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if schemaErr, ok := r.(*SchemaError); ok {
+				err = schemaErr
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	fn()
+	return nil
+}
@@ -0,0 +1,41 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/go-openapi/spec"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStubSchemaPanicsWithSchemaPath(t *testing.T) {
+	require := require.New(t)
+
+	schema := spec.Schema{
+		SchemaProps: spec.SchemaProps{
+			Type: spec.StringOrArray{"object"},
+			Properties: map[string]spec.Schema{
+				"data": {},
+			},
+		},
+	}
+
+	err := recoverSchemaError(func() { StubSchema(schema) })
+	require.IsType(&SchemaError{}, err)
+	require.Equal("#/properties/data", err.(*SchemaError).Path)
+}
+
+func TestPreflight(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("./testdata/responses.yaml", StubGeneratorOptions{})
+	require.NoError(err)
+
+	errs := stub.Preflight()
+	require.Len(errs, 1)
+	require.Contains(errs[0].Error(), "Get: /broken")
+
+	stub, err = NewStubGenerator("../petstore.yaml", StubGeneratorOptions{})
+	require.NoError(err)
+	require.Empty(stub.Preflight())
+}
@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+	"github.com/pkg/errors"
+)
+
+// DefaultStatusCode is the status code used when an
+// operation only defines a default response
+const DefaultStatusCode = 200
+
+// rangedResponses holds the ranged status code responses (1XX - 5XX)
+// of each operation keyed by the status code class (1 - 5)
+type rangedResponses map[*spec.Operation]map[int]spec.Response
+
+var rangedStatusCode = regexp.MustCompile(`^[1-5][xX][xX]$`)
+
+// LoadRangedResponses finds responses keyed by a status code range
+// i.e. "2XX" in the original document. go-openapi only understands exact
+// status codes and "default" so these responses are otherwise lost.
+// The responses are expanded and associated with the operations
+// of the expanded document.
+func LoadRangedResponses(original *loads.Document, expanded *loads.Document) (rangedResponses, error) {
+	var raw struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(original.Raw(), &raw); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling spec")
+	}
+
+	ranged := rangedResponses{}
+	for apiPath, methods := range raw.Paths {
+		pathItem, ok := expanded.Spec().Paths.Paths[apiPath]
+		if !ok {
+			continue
+		}
+		operations := PathItemOperations(pathItem)
+
+		for method, content := range methods {
+			operation, ok := operations[strings.ToUpper(method)]
+			if !ok {
+				continue
+			}
+
+			var rawOperation struct {
+				Responses map[string]json.RawMessage `json:"responses"`
+			}
+			if err := json.Unmarshal(content, &rawOperation); err != nil {
+				continue
+			}
+
+			for code, content := range rawOperation.Responses {
+				if !rangedStatusCode.MatchString(code) {
+					continue
+				}
+
+				response := spec.Response{}
+				if err := json.Unmarshal(content, &response); err != nil {
+					return nil, errors.Wrapf(err, "unmarshalling %v response of %v %v", code, method, apiPath)
+				}
+				if err := spec.ExpandResponseWithRoot(&response, original.Spec(), nil); err != nil {
+					return nil, errors.Wrapf(err, "expanding %v response of %v %v", code, method, apiPath)
+				}
+
+				class, _ := strconv.Atoi(code[:1])
+				if ranged[operation] == nil {
+					ranged[operation] = map[int]spec.Response{}
+				}
+				ranged[operation][class] = response
+			}
+		}
+	}
+
+	return ranged, nil
+}
@@ -0,0 +1,100 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Reload loads the spec and overlays again and replaces the ones
+// that the generator is using. The generator is unchanged if they
+// fail to load, or with StrictOverlay if the new overlay doesn't
+// match the spec. Reloading starts every sequence again.
+func (stub *StubGenerator) Reload() error {
+	state, err := loadStubState(stub.urlOrPath, stub.options)
+	if err != nil {
+		return err
+	}
+
+	if stub.options.StrictOverlay {
+		if errs := state.ValidateOverlay(); len(errs) != 0 {
+			messages := make([]string, len(errs))
+			for i, err := range errs {
+				messages[i] = err.Error()
+			}
+			return fmt.Errorf("the overlay has %v errors: %v", len(errs), strings.Join(messages, "; "))
+		}
+	}
+
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	stub.state = state
+	return nil
+}
+
+// Snapshot returns a generator that keeps the current spec and
+// overlay when this one is reloaded. Everything done for a single
+// request should use one snapshot so that it sees one version.
+func (stub *StubGenerator) Snapshot() *StubGenerator {
+	return &StubGenerator{
+		urlOrPath: stub.urlOrPath,
+		options:   stub.options,
+		state:     stub.current(),
+	}
+}
+
+// Files returns the local files that the generator was loaded
+// from: the spec, the overlay files and directories and the
+// files that overlay responses are read from
+func (stub *StubGenerator) Files() []string {
+	return append([]string{}, stub.current().files...)
+}
+
+// Watch polls the files that the generator was loaded from every
+// interval and reloads it when any of them change. onReload is called
+// with the result of every reload. Watch returns when stop is closed.
+func (stub *StubGenerator) Watch(interval time.Duration, stop <-chan struct{}, onReload func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := fingerprint(stub.Files())
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		current := fingerprint(stub.Files())
+		if current == last {
+			continue
+		}
+
+		// a failed reload isn't retried until the files change
+		// again because they're probably still being edited
+		err := stub.Reload()
+		last = fingerprint(stub.Files())
+		if err != nil {
+			last = current
+		}
+		if onReload != nil {
+			onReload(err)
+		}
+	}
+}
+
+// fingerprint summarises the modification time and
+// size of files so that changes can be detected
+func fingerprint(files []string) string {
+	result := ""
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			result += fmt.Sprintf("%v missing\n", file)
+			continue
+		}
+		result += fmt.Sprintf("%v %v %v\n", file, info.ModTime().UnixNano(), info.Size())
+	}
+	return result
+}
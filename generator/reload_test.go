@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeOverlay(t *testing.T, path string, content string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+paths:
+  /v1/pets:
+    get:
+      responses:
+        200:
+          raw: true
+          content: `+content+`
+`), 0644))
+}
+
+func TestReload(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "overlay")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	overlay := filepath.Join(dir, "overlay.yaml")
+	writeOverlay(t, overlay, "first")
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{Overlay: overlay})
+	require.NoError(err)
+	require.Equal([]string{"../petstore.yaml", overlay}, stub.Files())
+
+	body := func() string {
+		response, err := stub.StubResponse(Request{Path: "/v1/pets", Method: "GET"})
+		require.NoError(err)
+		return string(response.Body.([]byte))
+	}
+	require.Equal("first", body())
+
+	writeOverlay(t, overlay, "second")
+	require.NoError(stub.Reload())
+	require.Equal("second", body())
+
+	require.NoError(ioutil.WriteFile(overlay, []byte("paths: ["), 0644))
+	require.Error(stub.Reload())
+	require.Equal("second", body())
+}
+
+func TestSnapshotIsNotReloaded(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "overlay")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	overlay := filepath.Join(dir, "overlay.yaml")
+	writeOverlay(t, overlay, "first")
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{Overlay: overlay})
+	require.NoError(err)
+	snapshot := stub.Snapshot()
+
+	writeOverlay(t, overlay, "second")
+	require.NoError(stub.Reload())
+
+	body := func(stub *StubGenerator) string {
+		response, err := stub.StubResponse(Request{Path: "/v1/pets", Method: "GET"})
+		require.NoError(err)
+		return string(response.Body.([]byte))
+	}
+	require.Equal("second", body(stub))
+	require.Equal("first", body(snapshot))
+}
+
+func TestReloadStrictOverlay(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "overlay")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	overlay := filepath.Join(dir, "overlay.yaml")
+	writeOverlay(t, overlay, "first")
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{Overlay: overlay, StrictOverlay: true})
+	require.NoError(err)
+
+	require.NoError(ioutil.WriteFile(overlay, []byte(`
+paths:
+  /v1/missing:
+    get:
+      responses:
+        200:
+          raw: true
+          content: second
+`), 0644))
+	err = stub.Reload()
+	require.Error(err)
+	require.Contains(err.Error(), "/v1/missing")
+
+	response, err := stub.StubResponse(Request{Path: "/v1/pets", Method: "GET"})
+	require.NoError(err)
+	require.Equal("first", string(response.Body.([]byte)))
+}
+
+func TestWatch(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "overlay")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	overlay := filepath.Join(dir, "overlay.yaml")
+	writeOverlay(t, overlay, "first")
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{Overlay: overlay})
+	require.NoError(err)
+
+	reloads := make(chan error, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go stub.Watch(10*time.Millisecond, stop, func(err error) {
+		select {
+		case reloads <- err:
+		default:
+		}
+	})
+
+	// make sure the modification time changes on
+	// file systems with a coarse resolution
+	time.Sleep(20 * time.Millisecond)
+	writeOverlay(t, overlay, "second file")
+	require.NoError(os.Chtimes(overlay, time.Now().Add(time.Second), time.Now().Add(time.Second)))
+
+	select {
+	case err := <-reloads:
+		require.NoError(err)
+	case <-time.After(5 * time.Second):
+		require.FailNow("the overlay wasn't reloaded")
+	}
+
+	response, err := stub.StubResponse(Request{Path: "/v1/pets", Method: "GET"})
+	require.NoError(err)
+	require.Equal("second file", string(response.Body.([]byte)))
+}
@@ -0,0 +1,54 @@
+package generator
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/go-openapi/spec"
+)
+
+// Request describes the HTTP request that
+// a response is being stubbed for
+type Request struct {
+	Path   string
+	Method string
+	// Accept is the request's Accept header. It's used to
+	// choose the media type of the response.
+	Accept     string
+	Preference ResponsePreference
+	// Params are the path parameters of the request
+	Params map[string]string
+	Query  url.Values
+	Header http.Header
+	// Body is the parsed request body
+	Body interface{}
+}
+
+// ResponsePreference lets a client choose which of an
+// operation's responses is stubbed
+type ResponsePreference struct {
+	// StatusCode selects the response for a status code.
+	// Zero means there's no preference.
+	StatusCode int
+	// Example selects the response's example for a media type
+	// (i.e. application/json) to be returned instead of generated data
+	Example string
+}
+
+// StubbedResponse is everything needed to write
+// a stubbed response to a client
+type StubbedResponse struct {
+	StatusCode int
+	Headers    map[string]string
+	// MediaType is the negotiated media type of the body.
+	// It's empty when the response has no body.
+	MediaType string
+	Body      interface{}
+	// Schema is the schema that the body was generated from
+	Schema *spec.Schema
+	// Raw means the Body is a []byte to be written as is
+	Raw bool
+	// Delay is extra latency for the response from
+	// the overlay. nil means no extra delay.
+	Delay *Latency
+}
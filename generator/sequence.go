@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+// Sequence modes
+const (
+	// SequenceSequential steps through the responses
+	// once and then repeats the last response
+	SequenceSequential = "sequential"
+	// SequenceLoop steps through the responses and
+	// starts again from the first after the last
+	SequenceLoop = "loop"
+	// SequenceRandom picks a response at random
+	SequenceRandom = "random"
+	// SequenceWeighted picks a response at random
+	// in proportion to the response weights
+	SequenceWeighted = "weighted"
+)
+
+// Sequence is a list of responses that are served
+// one after another, i.e. 202, 202 and then 200 for
+// a polling endpoint.
+type Sequence struct {
+	// Mode is how the next response is chosen.
+	// Defaults to SequenceSequential.
+	Mode      string         `yaml:"mode"`
+	Responses []SequenceStep `yaml:"responses"`
+}
+
+// SequenceStep is a response in a sequence
+type SequenceStep struct {
+	Response `yaml:",inline"`
+	// Weight is the relative chance of the step being
+	// picked in weighted mode. Defaults to 1.
+	Weight *int `yaml:"weight"`
+}
+
+func (step SequenceStep) weight() int {
+	if step.Weight == nil {
+		return 1
+	}
+	return *step.Weight
+}
+
+// validate checks the sequence's mode and weights
+func (sequence *Sequence) validate() error {
+	switch sequence.Mode {
+	case "", SequenceSequential, SequenceLoop, SequenceRandom, SequenceWeighted:
+	default:
+		return fmt.Errorf("unknown sequence mode %q", sequence.Mode)
+	}
+
+	if len(sequence.Responses) == 0 {
+		return fmt.Errorf("sequence has no responses")
+	}
+
+	total := 0
+	for i, step := range sequence.Responses {
+		if step.weight() < 0 {
+			return fmt.Errorf("sequence response %v has a negative weight", i)
+		}
+		total += step.weight()
+		if step.Sequence != nil {
+			return fmt.Errorf("sequence response %v can't be a sequence", i)
+		}
+	}
+	if sequence.Mode == SequenceWeighted && total == 0 {
+		return fmt.Errorf("weighted sequence has no weight")
+	}
+
+	return nil
+}
+
+// sequenceCounters counts how many responses
+// each sequence has served
+type sequenceCounters struct {
+	mutex  sync.Mutex
+	counts map[*Sequence]int
+}
+
+// next returns the next response of the sequence
+func (counters *sequenceCounters) next(sequence *Sequence) Response {
+	counters.mutex.Lock()
+	defer counters.mutex.Unlock()
+
+	if counters.counts == nil {
+		counters.counts = map[*Sequence]int{}
+	}
+	count := counters.counts[sequence]
+	counters.counts[sequence] = count + 1
+
+	steps := sequence.Responses
+	switch sequence.Mode {
+	case SequenceLoop:
+		return steps[count%len(steps)].Response
+
+	case SequenceRandom:
+		return steps[rand.Intn(len(steps))].Response
+
+	case SequenceWeighted:
+		total := 0
+		for _, step := range steps {
+			total += step.weight()
+		}
+		n := rand.Intn(total)
+		for _, step := range steps {
+			if n < step.weight() {
+				return step.Response
+			}
+			n -= step.weight()
+		}
+		return steps[len(steps)-1].Response
+
+	default:
+		if count >= len(steps) {
+			count = len(steps) - 1
+		}
+		return steps[count].Response
+	}
+}
+
+// reset starts every sequence again from it's first response
+func (counters *sequenceCounters) reset() {
+	counters.mutex.Lock()
+	defer counters.mutex.Unlock()
+	counters.counts = nil
+}
+
+// ResetSequences starts every overlay sequence again
+// from it's first response
+func (stub *StubGenerator) ResetSequences() {
+	stub.current().sequences.reset()
+}
@@ -0,0 +1,70 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceModes(t *testing.T) {
+	require := require.New(t)
+
+	steps := []SequenceStep{
+		{Response: Response{Status: 202}},
+		{Response: Response{Status: 200}},
+	}
+	counters := &sequenceCounters{}
+
+	sequential := &Sequence{Responses: steps}
+	require.Equal(202, counters.next(sequential).Status)
+	require.Equal(200, counters.next(sequential).Status)
+	require.Equal(200, counters.next(sequential).Status)
+
+	loop := &Sequence{Mode: SequenceLoop, Responses: steps}
+	require.Equal(202, counters.next(loop).Status)
+	require.Equal(200, counters.next(loop).Status)
+	require.Equal(202, counters.next(loop).Status)
+
+	zero := 0
+	weighted := &Sequence{Mode: SequenceWeighted, Responses: []SequenceStep{
+		{Response: Response{Status: 500}, Weight: &zero},
+		{Response: Response{Status: 200}},
+	}}
+	for i := 0; i < 10; i++ {
+		require.Equal(200, counters.next(weighted).Status)
+	}
+
+	counters.reset()
+	require.Equal(202, counters.next(sequential).Status)
+}
+
+func TestSequenceValidation(t *testing.T) {
+	require := require.New(t)
+
+	require.Error((&Sequence{Mode: "sometimes", Responses: []SequenceStep{{}}}).validate())
+	require.Error((&Sequence{}).validate())
+
+	zero := 0
+	require.Error((&Sequence{Mode: SequenceWeighted, Responses: []SequenceStep{{Weight: &zero}}}).validate())
+}
+
+func TestStubResponseWithSequence(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{Overlay: "testdata/sequence.yaml"})
+	require.NoError(err)
+
+	request := Request{Path: "/v1/pets", Method: "POST"}
+	codes := []int{}
+	for i := 0; i < 3; i++ {
+		response, err := stub.StubResponse(request)
+		require.NoError(err)
+		codes = append(codes, response.StatusCode)
+	}
+	require.Equal([]int{500, 201, 201}, codes)
+
+	stub.ResetSequences()
+	response, err := stub.StubResponse(request)
+	require.NoError(err)
+	require.Equal(500, response.StatusCode)
+}
@@ -0,0 +1,156 @@
+package generator
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-openapi/spec"
+	"github.com/pkg/errors"
+)
+
+// TemplateData is available to overlay content templates
+// i.e. {{ .Params.id }} or {{ .Query.Get "page" }}
+type TemplateData struct {
+	Path   string
+	Method string
+	// Params are the path parameters of the request
+	Params map[string]string
+	Query  url.Values
+	Header http.Header
+	// Body is the parsed request body
+	Body interface{}
+
+	// definitions are the spec's definitions for random
+	definitions spec.Definitions
+}
+
+// templateFuncs returns the helper functions available to overlay
+// content templates. random can stub the spec's definitions.
+func templateFuncs(definitions spec.Definitions) template.FuncMap {
+	return template.FuncMap{
+		"uuid": newUUID,
+		"now":  time.Now,
+		"json": toJSON,
+		"fake": Fake,
+		// random returns random data for a definition from the spec
+		// i.e. {{ random "Pet" }} or {{ random "#/definitions/Pet" }}
+		// or for a schema type and optional format i.e.
+		// {{ random "string" "date-time" }}
+		"random": func(name string, format ...string) (interface{}, error) {
+			schema, err := randomSchema(definitions, name, format...)
+			if err != nil {
+				return nil, err
+			}
+			return StubSchema(*schema), nil
+		},
+	}
+}
+
+// randomSchema finds the definition or builds the schema
+// that the random template function stubs
+func randomSchema(definitions spec.Definitions, name string, format ...string) (*spec.Schema, error) {
+	if schema, ok := definitions[strings.TrimPrefix(name, "#/definitions/")]; ok {
+		return &schema, nil
+	}
+	if strings.HasPrefix(name, "#/") {
+		return nil, fmt.Errorf("unknown definition %q", name)
+	}
+
+	switch name {
+	case "string", "number", "integer", "boolean":
+		schema := spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{name}}}
+		if len(format) != 0 {
+			schema.Format = format[0]
+		}
+		return &schema, nil
+	}
+
+	return nil, fmt.Errorf("%q is not a definition or a primitive type", name)
+}
+
+// NewTemplateData returns the template data for a request
+// to a spec with the given definitions
+func NewTemplateData(request Request, definitions spec.Definitions) TemplateData {
+	return TemplateData{
+		Path:   request.Path,
+		Method: request.Method,
+		Params: request.Params,
+		Query:  request.Query,
+		Header: request.Header,
+		Body:   request.Body,
+
+		definitions: definitions,
+	}
+}
+
+// RenderTemplate renders overlay content as a Go template
+func RenderTemplate(content string, data TemplateData) (string, error) {
+	if !strings.Contains(content, "{{") {
+		return content, nil
+	}
+
+	tmpl, err := template.New("content").Funcs(templateFuncs(data.definitions)).Option("missingkey=zero").Parse(content)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing template")
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", errors.Wrap(err, "rendering template")
+	}
+	return buf.String(), nil
+}
+
+// RenderResponse returns a copy of the response overlay with
+// it's content and header values rendered as templates
+func RenderResponse(response Response, data TemplateData) (Response, error) {
+	rendered := response
+
+	content, err := RenderTemplate(response.Content, data)
+	if err != nil {
+		return rendered, errors.Wrap(err, "rendering overlay content")
+	}
+	rendered.Content = content
+
+	if response.Headers != nil {
+		rendered.Headers = map[string]*string{}
+		for name, value := range response.Headers {
+			if value == nil {
+				rendered.Headers[name] = nil
+				continue
+			}
+			header, err := RenderTemplate(*value, data)
+			if err != nil {
+				return rendered, errors.Wrapf(err, "rendering overlay header %v", name)
+			}
+			rendered.Headers[name] = &header
+		}
+	}
+
+	return rendered, nil
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// toJSON encodes a value as JSON so that it can be
+// safely placed into JSON content
+func toJSON(value interface{}) (string, error) {
+	content, err := json.Marshal(value)
+	return string(content), err
+}
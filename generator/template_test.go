@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	require := require.New(t)
+
+	data := TemplateData{
+		Params: map[string]string{"petId": "7"},
+		Query:  url.Values{"page": {"2"}},
+		Body:   map[string]interface{}{"name": "rex"},
+	}
+
+	content, err := RenderTemplate(`{"id": {{ .Params.petId }}, "name": {{ json .Body.name }}, "page": "{{ .Query.Get "page" }}"}`, data)
+	require.NoError(err)
+	require.JSONEq(`{"id": 7, "name": "rex", "page": "2"}`, content)
+}
+
+func TestRenderTemplateFunctions(t *testing.T) {
+	require := require.New(t)
+
+	content, err := RenderTemplate(`{{ uuid }}`, TemplateData{})
+	require.NoError(err)
+	require.Regexp(regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), content)
+
+	content, err = RenderTemplate(`{{ random "string" "date" }}`, TemplateData{})
+	require.NoError(err)
+	require.Regexp(regexp.MustCompile(ISO8601_DATE_STRING_RE), content)
+
+	content, err = RenderTemplate(`{{ fake "email" }}`, TemplateData{})
+	require.NoError(err)
+	require.Contains(content, "@")
+
+	_, err = RenderTemplate(`{{ fake "nonsense" }}`, TemplateData{})
+	require.Error(err)
+
+	for kind := range fakes {
+		value, err := Fake(kind)
+		require.NoError(err)
+		require.NotEmpty(value, kind)
+	}
+}
+
+func TestRenderTemplateRandomDefinition(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{})
+	require.NoError(err)
+	data := NewTemplateData(Request{}, stub.current().spec.Definitions)
+
+	for _, name := range []string{"Pet", "#/definitions/Pet"} {
+		content, err := RenderTemplate(`{{ json (random "`+name+`") }}`, data)
+		require.NoError(err)
+
+		var pet map[string]interface{}
+		require.NoError(json.Unmarshal([]byte(content), &pet))
+		require.Contains(pet, "id")
+		require.Contains(pet, "name")
+	}
+
+	_, err = RenderTemplate(`{{ random "#/definitions/Nope" }}`, data)
+	require.Error(err)
+
+	_, err = RenderTemplate(`{{ random "Nope" }}`, data)
+	require.Error(err)
+}
+
+func TestStubResponseWithTemplatedOverlay(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("../petstore.yaml", StubGeneratorOptions{Overlay: "testdata/templates.yaml"})
+	require.NoError(err)
+
+	response, err := stub.StubResponse(Request{Path: "/v1/pets/7", Method: "GET"})
+	require.NoError(err)
+	require.Len(response.Body, 1)
+	pet := response.Body.([]interface{})[0].(map[string]interface{})
+	require.Equal(float64(7), pet["id"])
+	require.IsType("", pet["name"])
+}
@@ -0,0 +1,250 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+)
+
+// ValidateOverlay checks the overlay against the spec so that
+// mistakes are found at startup rather than silently ignored.
+// An error is returned for every overlay path, method or status
+// code that the spec doesn't have and for every response content
+// that isn't JSON or doesn't match the response schema. Paths that
+// are regular expressions can only have their content checked.
+func (stub *StubGenerator) ValidateOverlay() []error {
+	return stub.current().ValidateOverlay()
+}
+
+// OverlayConflicts returns a message for every overlay response
+// or latency that replaced one from another overlay file. Later
+// files replacing earlier ones is expected so these aren't errors.
+func (stub *StubGenerator) OverlayConflicts() []error {
+	return stub.current().overlay.Conflicts()
+}
+
+// ValidateOverlay implements StubGenerator.ValidateOverlay
+func (stub *stubState) ValidateOverlay() []error {
+	paths := make([]string, 0, len(stub.overlay.Paths))
+	for path := range stub.overlay.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	errs := []error{}
+	for _, path := range paths {
+		specPaths, checkSpec := stub.overlaySpecPaths(path)
+		if checkSpec && len(specPaths) == 0 {
+			errs = append(errs, fmt.Errorf("overlay path %v doesn't match any path in the spec", path))
+			continue
+		}
+
+		for method, operationOverlay := range stub.overlay.Paths[path].operations() {
+			operations := []*spec.Operation{}
+			for _, specPath := range specPaths {
+				if operation, ok := PathItemOperations(stub.spec.Paths.Paths[specPath])[method]; ok {
+					operations = append(operations, operation)
+				}
+			}
+			if checkSpec && len(operations) == 0 {
+				errs = append(errs, fmt.Errorf("overlay %v %v: the spec has no %v operation for the path", method, path, method))
+				continue
+			}
+
+			for statusCode, responses := range operationOverlay.Responses {
+				for i, response := range responses {
+					steps := []Response{response}
+					if response.Sequence != nil {
+						steps = []Response{}
+						for _, step := range response.Sequence.Responses {
+							steps = append(steps, step.Response)
+						}
+					}
+
+					for j, step := range steps {
+						name := fmt.Sprintf("overlay %v %v %v response", method, path, statusCode)
+						if len(responses) > 1 {
+							name += fmt.Sprintf(" %v", i)
+						}
+						if response.Sequence != nil {
+							name += fmt.Sprintf(" sequence step %v", j)
+						}
+
+						if !checkSpec {
+							if err := checkContentJSON(step, nil); err != nil {
+								errs = append(errs, fmt.Errorf("%v: %v", name, err))
+							}
+							continue
+						}
+
+						for _, operation := range operations {
+							if err := stub.validateOverlayResponse(operation, statusCode, step); err != nil {
+								errs = append(errs, fmt.Errorf("%v: %v", name, err))
+								break
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+
+	return errs
+}
+
+// overlaySpecPaths returns the spec paths that an overlay path
+// applies to. The spec isn't checked for regular expressions because
+// they can't be compared to spec paths.
+func (stub *stubState) overlaySpecPaths(path string) ([]string, bool) {
+	switch {
+	case strings.HasPrefix(path, "^"):
+		return nil, false
+
+	case strings.Contains(path, "*"):
+		glob := globToRegexp(path)
+		specPaths := []string{}
+		for specPath := range stub.spec.Paths.Paths {
+			if glob.MatchString(specPath) {
+				specPaths = append(specPaths, specPath)
+			}
+		}
+		sort.Strings(specPaths)
+		return specPaths, true
+
+	default:
+		// a template's parameters match the parameters
+		// of the spec path because they have no slashes
+		route, err := stub.FindPathItem(path)
+		if err != nil {
+			return nil, true
+		}
+		return []string{route.Path}, true
+	}
+}
+
+// validateOverlayResponse checks that the operation has a response for
+// the status code and that the overlay content fits it's schema
+func (stub *stubState) validateOverlayResponse(operation *spec.Operation, statusCode int, response Response) error {
+	specResponse, _, err := stub.FindResponse(operation, ResponsePreference{StatusCode: statusCode})
+	if err != nil {
+		return fmt.Errorf("the spec has no %v response", statusCode)
+	}
+
+	if response.Status != 0 {
+		statusCode = response.Status
+		specResponse, _, err = stub.FindResponse(operation, ResponsePreference{StatusCode: statusCode})
+		if err != nil {
+			// a forced status code without a spec
+			// response is served from the overlay alone
+			return checkOverlayOnlyResponse(statusCode, response)
+		}
+	}
+
+	if response.Raw || isTemplate(response) {
+		return nil
+	}
+
+	if !HasBody(statusCode, *specResponse) {
+		if response.Content != "" || len(response.Set) != 0 || len(response.Patch) != 0 {
+			return fmt.Errorf("the %v response has no body to overlay", statusCode)
+		}
+		return nil
+	}
+
+	if err := checkContentJSON(response, specResponse.Schema); err != nil {
+		return err
+	}
+
+	data, err := overlayBase(response, *specResponse.Schema)
+	if err != nil {
+		return fmt.Errorf("the content can't be checked against the %v response schema: %v", statusCode, err)
+	}
+	if err := ApplyResponseOverlay(response, &data); err != nil {
+		return err
+	}
+	if err := validate.AgainstSchema(specResponse.Schema, normalizeJSON(data), strfmt.Default); err != nil {
+		return fmt.Errorf("content doesn't match the response schema: %v", err)
+	}
+
+	return nil
+}
+
+// overlayBase returns the data that the overlay is applied to when it's
+// checked. Content that replaces the whole value, because it isn't an
+// object or the schema isn't one, is checked alone. Anything else is
+// applied to the schema's minimal stub so that the result is the same
+// every time and only the overlay can make it invalid.
+func overlayBase(response Response, schema spec.Schema) (interface{}, error) {
+	var content interface{}
+	if err := json.Unmarshal([]byte(response.Content), &content); err == nil {
+		if _, ok := content.(map[string]interface{}); !ok {
+			return nil, nil
+		}
+		if !schema.Type.Contains("object") && (len(schema.Type) != 0 || len(schema.Properties) == 0) {
+			return nil, nil
+		}
+	}
+
+	var data interface{}
+	if err := recoverSchemaError(func() { data = MinimalStub(schema) }); err != nil {
+		return nil, err
+	}
+	if err := validate.AgainstSchema(&schema, normalizeJSON(data), strfmt.Default); err != nil {
+		return nil, fmt.Errorf("the schema's minimal stub doesn't match it: %v", err)
+	}
+	return data, nil
+}
+
+// checkOverlayOnlyResponse checks an overlay for a status code that the
+// spec doesn't define in the same way that it's served: JSON content with
+// set and patch applied or any other content as plain text
+func checkOverlayOnlyResponse(statusCode int, response Response) error {
+	if response.Raw || isTemplate(response) {
+		return nil
+	}
+	if !statusHasBody(statusCode) {
+		if hasOverlayBody(response) {
+			return fmt.Errorf("a %v response has no body so the content is ignored", statusCode)
+		}
+		return nil
+	}
+	if response.Content != "" && !json.Valid([]byte(response.Content)) {
+		if len(response.Set) != 0 || len(response.Patch) != 0 {
+			return fmt.Errorf("set and patch are ignored because the content isn't JSON")
+		}
+		return nil
+	}
+	var data interface{}
+	return ApplyResponseOverlay(response, &data)
+}
+
+// checkContentJSON checks that the content is JSON unless
+// the response is raw, templated or the schema is a string
+func checkContentJSON(response Response, schema *spec.Schema) error {
+	if response.Content == "" || response.Raw || isTemplate(response) {
+		return nil
+	}
+	if schema != nil && schema.Type.Contains("string") {
+		return nil
+	}
+	var content interface{}
+	if err := json.Unmarshal([]byte(response.Content), &content); err != nil {
+		return fmt.Errorf("content isn't valid JSON: %v", err)
+	}
+	return nil
+}
+
+// isTemplate reports whether the content is a template which
+// can't be checked until it's rendered for a request
+func isTemplate(response Response) bool {
+	return strings.Contains(response.Content, "{{")
+}
@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOverlay(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("testdata/responses.yaml", StubGeneratorOptions{Overlay: "testdata/validation.yaml"})
+	require.NoError(err)
+
+	messages := []string{}
+	for _, err := range stub.ValidateOverlay() {
+		messages = append(messages, err.Error())
+	}
+
+	require.Equal([]string{
+		"overlay GET /ranged 200 response 2: a 304 response has no body so the content is ignored",
+		"overlay GET /ranged 200 response 3: set and patch are ignored because the content isn't JSON",
+		"overlay GET /ranged 200 response 4: applying response overlay patch: patch operation 0 (replace /missing): /missing not found",
+		"overlay GET /ranged 404 response 0: content doesn't match the response schema: validation failure list:\nmessage in body must be of type string: \"number\"",
+		"overlay GET /ranged 404 response 1: content isn't valid JSON: invalid character 'o' in literal null (expecting 'u')",
+		"overlay GET /ranged 500 response: the spec has no 500 response",
+		"overlay GET ^/anything$ 200 response: content isn't valid JSON: invalid character 'o' in literal null (expecting 'u')",
+		"overlay POST /ranged: the spec has no POST operation for the path",
+		"overlay path /missing doesn't match any path in the spec",
+		"overlay path /nothing/* doesn't match any path in the spec",
+	}, messages)
+}
+
+func TestValidateOverlayIsDeterministic(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := NewStubGenerator("testdata/pets.yaml", StubGeneratorOptions{Overlay: "testdata/pets-overlay.yaml"})
+	require.NoError(err)
+
+	expected := []string{
+		"overlay GET /pet 200 response 2: content doesn't match the response schema: validation failure list:\n.name in body is required",
+		"overlay GET /pets 200 response 1: content doesn't match the response schema: validation failure list:\n.name in body is required",
+	}
+	for i := 0; i < 20; i++ {
+		messages := []string{}
+		for _, err := range stub.ValidateOverlay() {
+			messages = append(messages, err.Error())
+		}
+		require.Equal(expected, messages)
+	}
+}
@@ -1,36 +1,39 @@
 module github.com/place1/openapi-mock-server
 
+go 1.27.1
+
+require (
+	github.com/go-openapi/jsonpointer v0.17.2
+	github.com/go-openapi/loads v0.17.2
+	github.com/go-openapi/spec v0.17.2
+	github.com/go-openapi/strfmt v0.17.2
+	github.com/go-openapi/validate v0.17.2
+	github.com/manveru/faker v0.0.0-20171103152722-9fbc68a78c4d
+	github.com/pkg/errors v0.8.0
+	github.com/stretchr/testify v1.2.2
+	gopkg.in/alecthomas/kingpin.v2 v2.2.6
+	gopkg.in/yaml.v2 v2.2.2
+)
+
 require (
-	github.com/Pallinder/go-randomdata v1.1.0 // indirect
+	github.com/PuerkitoBio/purell v1.1.0 // indirect
+	github.com/PuerkitoBio/urlesc v0.0.0-20170810143723-de5bf2ad4578 // indirect
 	github.com/alecthomas/template v0.0.0-20160405071501-a0175ee3bccc // indirect
 	github.com/alecthomas/units v0.0.0-20151022065526-2efee857e7cf // indirect
-	github.com/cstockton/go-conv v0.0.0-20170524002450-66a2b2ba36e1 // indirect
+	github.com/asaskevich/govalidator v0.0.0-20180720115003-f9ffefc3facf // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/globalsign/mgo v0.0.0-20181015135952-eeefdecb41b8 // indirect
 	github.com/go-openapi/analysis v0.17.2 // indirect
 	github.com/go-openapi/errors v0.17.2 // indirect
-	github.com/go-openapi/jsonpointer v0.17.2
 	github.com/go-openapi/jsonreference v0.17.2 // indirect
-	github.com/go-openapi/loads v0.17.2
 	github.com/go-openapi/runtime v0.17.2 // indirect
-	github.com/go-openapi/spec v0.17.2
-	github.com/go-openapi/strfmt v0.17.2
-	github.com/go-openapi/stubs v0.0.0-20170429194734-98bff229fc7b // indirect
 	github.com/go-openapi/swag v0.17.2 // indirect
-	github.com/go-openapi/validate v0.17.2
 	github.com/google/uuid v1.1.0 // indirect
-	github.com/imdario/mergo v0.3.6
-	github.com/manveru/faker v0.0.0-20171103152722-9fbc68a78c4d // indirect
-	github.com/pkg/errors v0.8.0
-	github.com/sirupsen/logrus v1.2.0
-	github.com/spf13/cobra v0.0.3 // indirect
-	github.com/spf13/pflag v1.0.3 // indirect
-	github.com/stretchr/testify v1.2.2
-	github.com/urfave/cli v1.20.0 // indirect
-	github.com/zach-klippenstein/goregen v0.0.0-20160303162051-795b5e3961ea // indirect
-	golang.org/x/crypto v0.0.0-20181127143415-eb0de9b17e85 // indirect
+	github.com/kr/pretty v0.3.1 // indirect
+	github.com/mailru/easyjson v0.0.0-20180823135443-60711f1a8329 // indirect
+	github.com/mitchellh/mapstructure v1.1.2 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/net v0.0.0-20181114220301-adae6a3d119a // indirect
-	golang.org/x/sys v0.0.0-20181128092732-4ed8d59d0b35 // indirect
-	gopkg.in/alecthomas/kingpin.v2 v2.2.6
+	golang.org/x/text v0.3.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
-	gopkg.in/yaml.v2 v2.2.2
 )
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"time"
 
 	"github.com/place1/openapi-mock-server/server"
 
@@ -14,41 +15,135 @@ var (
 	serveSpec     = kingpin.Arg("openapi-spec", "the path to an openapi spec yaml file").Required().String()
 	serveHost     = kingpin.Flag("host", "the host or ip address that the server should listen on.").Default("127.0.0.1").String()
 	servePort     = kingpin.Flag("port", "the port that the server should listen on.").Default("8000").Int()
-	serveOverlay  = kingpin.Flag("overlay", "path to an overlay.yaml file.").Default("").String()
+	serveOverlay  = kingpin.Flag("overlay", "path to an overlay.yaml file or a directory of them. can be repeated. later overlays replace earlier ones.").PlaceHolder("PATH").Strings()
+	serveStrict   = kingpin.Flag("strict-overlay", "fail to start if the overlay doesn't match the spec instead of logging warnings.").Bool()
 	serveBasePath = kingpin.Flag("base-path", "override the basePath defined in the spec. defaults to the value defined in the spec.").Default("").String()
+	serveMaxBody  = kingpin.Flag("max-body-size", "the largest request body the server will accept.").Default("10MB").Bytes()
+	serveAutoHead = kingpin.Flag("auto-head", "serve HEAD requests using the GET operation when the spec doesn't define HEAD. disable with --no-auto-head.").Default("true").Bool()
+	serveAutoOpts = kingpin.Flag("auto-options", "answer OPTIONS requests with an Allow header when the spec doesn't define OPTIONS. disable with --no-auto-options.").Default("true").Bool()
+	serveLatency  = kingpin.Flag("latency", "delay every response. a duration (200ms), a range (100ms-500ms) or a normal distribution (normal(300ms,50ms)).").Default("").String()
+	serveFaults   = kingpin.Flag("faults", "path to a faults.yaml file of faults to inject into responses.").Default("").String()
+	serveWatch    = kingpin.Flag("watch", "reload the spec and overlays when their files change.").Bool()
+	serveInterval = kingpin.Flag("watch-interval", "how often to check the spec and overlay files for changes.").Default("1s").Duration()
+
+	corsOrigins       = kingpin.Flag("cors-origin", "an origin allowed to make cross origin requests. can be repeated. defaults to any origin.").PlaceHolder("ORIGIN").Strings()
+	corsHeaders       = kingpin.Flag("cors-header", "a header allowed in cross origin requests. can be repeated. defaults to the headers requested by the preflight.").PlaceHolder("HEADER").Strings()
+	corsExposeHeaders = kingpin.Flag("cors-expose-header", "a response header that browsers may read. can be repeated.").PlaceHolder("HEADER").Strings()
+	corsCredentials   = kingpin.Flag("cors-credentials", "allow cross origin requests to include credentials.").Bool()
+	corsMaxAge        = kingpin.Flag("cors-max-age", "how long in seconds browsers may cache preflight responses.").Default("0").Int()
 )
 
 func main() {
 	kingpin.Parse()
 	Runmockserver(Options{
-		Spec:     *serveSpec,
-		Host:     *serveHost,
-		Port:     *servePort,
-		Overlay:  *serveOverlay,
-		BasePath: *serveBasePath,
+		Spec:          *serveSpec,
+		Host:          *serveHost,
+		Port:          *servePort,
+		Overlays:      *serveOverlay,
+		StrictOverlay: *serveStrict,
+		BasePath:      *serveBasePath,
+		MaxBodySize:   int64(*serveMaxBody),
+		AutoHead:      *serveAutoHead,
+		AutoOptions:   *serveAutoOpts,
+		Latency:       *serveLatency,
+		Faults:        *serveFaults,
+		Watch:         *serveWatch,
+		WatchInterval: *serveInterval,
+		CORS: server.CORSOptions{
+			AllowedOrigins:   *corsOrigins,
+			AllowedHeaders:   *corsHeaders,
+			ExposedHeaders:   *corsExposeHeaders,
+			AllowCredentials: *corsCredentials,
+			MaxAge:           *corsMaxAge,
+		},
 	})
 }
 
 type Options struct {
 	Spec     string
-	Overlay  string
-	BasePath string
-	Host     string
-	Port     int
+	Overlays []string
+	// StrictOverlay fails startup when the overlay
+	// doesn't validate against the spec
+	StrictOverlay bool
+	BasePath      string
+	Host          string
+	Port          int
+	MaxBodySize   int64
+	AutoHead      bool
+	AutoOptions   bool
+	Latency       string
+	Faults        string
+	// Watch reloads the spec and overlays every
+	// WatchInterval if their files have changed
+	Watch         bool
+	WatchInterval time.Duration
+	CORS          server.CORSOptions
 }
 
 func Runmockserver(options Options) {
 	stub, err := generator.NewStubGenerator(options.Spec, generator.StubGeneratorOptions{
-		Overlay:  options.Overlay,
-		BasePath: options.BasePath,
+		Overlays:      options.Overlays,
+		BasePath:      options.BasePath,
+		StrictOverlay: options.StrictOverlay,
 	})
 	if err != nil {
 		log.Fatalln(err)
 	}
 
+	for _, conflict := range stub.OverlayConflicts() {
+		log.Printf("info: %v", conflict)
+	}
+
+	overlayErrs := stub.ValidateOverlay()
+	for _, err := range overlayErrs {
+		log.Printf("warning: %v", err)
+	}
+	if options.StrictOverlay && len(overlayErrs) != 0 {
+		log.Fatalf("the overlay has %v errors and --strict-overlay is set", len(overlayErrs))
+	}
+
+	latency, err := generator.ParseLatency(options.Latency)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var faults *server.Faults
+	if options.Faults != "" {
+		faults, err = server.LoadFaultsFile(options.Faults)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	for _, err := range stub.Preflight() {
+		log.Printf("warning: %v", err)
+	}
+
+	if options.Watch {
+		go stub.Watch(options.WatchInterval, nil, func(err error) {
+			if err != nil {
+				log.Printf("reloading failed, still serving the previous version: %v", err)
+				return
+			}
+			log.Println("reloaded the spec and overlays")
+			for _, err := range stub.ValidateOverlay() {
+				log.Printf("warning: %v", err)
+			}
+			for _, err := range stub.Preflight() {
+				log.Printf("warning: %v", err)
+			}
+		})
+	}
+
 	server := server.OpenAPIMockServer(stub, &server.Options{
-		Host: options.Host,
-		Port: options.Port,
+		Host:        options.Host,
+		Port:        options.Port,
+		MaxBodySize: options.MaxBodySize,
+		AutoHead:    options.AutoHead,
+		AutoOptions: options.AutoOptions,
+		Latency:     latency,
+		Faults:      faults,
+		CORS:        options.CORS,
 	})
 
 	log.Printf("listening on %v:%v\n", options.Host, options.Port)
@@ -14,10 +14,12 @@ func TestStubResponse(t *testing.T) {
 	stub, err := generator.NewStubGenerator("./petstore.yaml", generator.StubGeneratorOptions{})
 	require.NoError(err)
 
-	data, err := stub.StubResponse("/v1/pets", "GET")
+	response, err := stub.StubResponse(generator.Request{Path: "/v1/pets", Method: "GET"})
 	require.NoError(err)
 
-	require.NotNil(data, "data should not be nil")
+	require.Equal(200, response.StatusCode)
+	require.Equal("application/json", response.MediaType)
+	require.NotNil(response.Body, "data should not be nil")
 }
 
 func TestStubResponseWithOverlay(t *testing.T) {
@@ -29,6 +31,20 @@ func TestStubResponseWithOverlay(t *testing.T) {
 	})
 	require.NoError(err)
 
-	_, err = stub.StubResponse("/test-base-path/pets", "GET")
+	_, err = stub.StubResponse(generator.Request{Path: "/test-base-path/pets", Method: "GET"})
 	require.NoError(err)
 }
+
+func TestStubResponseWithoutSchema(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := generator.NewStubGenerator("./petstore.yaml", generator.StubGeneratorOptions{})
+	require.NoError(err)
+
+	response, err := stub.StubResponse(generator.Request{Path: "/v1/pets", Method: "POST"})
+	require.NoError(err)
+
+	require.Equal(201, response.StatusCode)
+	require.Empty(response.MediaType)
+	require.Nil(response.Body, "responses without a schema should have no body")
+}
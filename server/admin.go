@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/place1/openapi-mock-server/generator"
+)
+
+// AdminPathPrefix is the path prefix of the endpoints used
+// to control the mock server while it's running
+const AdminPathPrefix = "/__mock/"
+
+// admin serves the admin endpoints and passes every
+// other request through to the handler
+func admin(handler http.Handler, stub *generator.StubGenerator, options *Options) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(AdminPathPrefix+"faults", faultsEndpoint(options.Faults))
+	mux.HandleFunc(AdminPathPrefix+"sequences/reset", resetSequencesEndpoint(stub))
+
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.URL.Path, AdminPathPrefix) {
+			handler.ServeHTTP(res, req)
+			return
+		}
+		mux.ServeHTTP(res, req)
+	})
+}
+
+type faultsStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+// faultsEndpoint reports whether fault injection is enabled
+// on GET and enables or disables it on PUT or POST
+// with a body like {"enabled": false}
+func faultsEndpoint(faults *Faults) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if faults == nil {
+			http.Error(res, "no faults file was loaded", http.StatusNotFound)
+			return
+		}
+
+		switch req.Method {
+		case "GET":
+		case "PUT", "POST":
+			status := faultsStatus{}
+			if err := json.NewDecoder(req.Body).Decode(&status); err != nil {
+				http.Error(res, "expected a body like {\"enabled\": true}", http.StatusBadRequest)
+				return
+			}
+			faults.SetEnabled(status.Enabled)
+		default:
+			res.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(res).Encode(faultsStatus{Enabled: faults.IsEnabled()})
+	}
+}
+
+// resetSequencesEndpoint starts every overlay sequence
+// again from it's first response on POST
+func resetSequencesEndpoint(stub *generator.StubGenerator) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" {
+			res.Header().Set("Allow", "POST")
+			http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		stub.ResetSequences()
+		res.WriteHeader(http.StatusNoContent)
+	}
+}
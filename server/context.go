@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/place1/openapi-mock-server/generator"
+
+	"github.com/go-openapi/spec"
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxBodySize is the request body size limit
+// used when Options.MaxBodySize isn't set
+const DefaultMaxBodySize = 10 << 20
+
+type requestContextKey struct{}
+
+// RequestContext carries the state shared between the
+// middleware and handler for a single request
+type RequestContext struct {
+	// Stub is the generator that the request is served with.
+	// It's a snapshot so that a reload part way through the
+	// request doesn't change the spec and overlay it sees.
+	Stub *generator.StubGenerator
+	// Route is the matched spec path and operation.
+	// It's nil if the request didn't match the spec.
+	Route *generator.Route
+	// Body is the buffered request body
+	Body []byte
+	// ParsedBody is the decoded request body or nil if
+	// the body was empty or couldn't be decoded
+	ParsedBody interface{}
+}
+
+// GetRequestContext returns the RequestContext for a request
+// or nil if the request hasn't passed through the requestContext middleware
+func GetRequestContext(req *http.Request) *RequestContext {
+	ctx, _ := req.Context().Value(requestContextKey{}).(*RequestContext)
+	return ctx
+}
+
+// requestContext buffers the request body, matches the request
+// to the spec and stores the result in the request's context
+// so that every later middleware and the handler can use it.
+// A RequestContext that's already on the request (i.e. from
+// recovery) is filled in so that outer middleware can see it.
+func requestContext(handler http.Handler, generator *generator.StubGenerator, maxBodySize int64) http.Handler {
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ctx := GetRequestContext(req)
+		if ctx == nil {
+			ctx = &RequestContext{}
+			req = req.WithContext(context.WithValue(req.Context(), requestContextKey{}, ctx))
+		}
+
+		if req.Body != nil {
+			body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxBodySize+1))
+			req.Body.Close()
+			if err != nil {
+				log.Println(errors.Wrap(err, "reading request body"))
+				http.Error(res, "unable to read request body", http.StatusBadRequest)
+				return
+			}
+			if int64(len(body)) > maxBodySize {
+				http.Error(res, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			ctx.Body = body
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		ctx.Stub = generator.Snapshot()
+		if route, err := ctx.Stub.FindRoute(req.URL.Path, req.Method); err == nil {
+			ctx.Route = route
+			if len(ctx.Body) != 0 {
+				ctx.ParsedBody, _ = DecodeBody(req.Header.Get("Content-Type"), ctx.Body, bodySchema(route))
+			}
+		}
+
+		handler.ServeHTTP(res, req)
+	})
+}
+
+// requestStub returns the request's snapshot of the generator, or
+// the generator itself if the request has no RequestContext
+func requestStub(req *http.Request, stub *generator.StubGenerator) *generator.StubGenerator {
+	if ctx := GetRequestContext(req); ctx != nil && ctx.Stub != nil {
+		return ctx.Stub
+	}
+	return stub
+}
+
+// bodySchema returns the schema of the route's body parameter
+func bodySchema(route *generator.Route) *spec.Schema {
+	for _, parameter := range route.Operation.Parameters {
+		if parameter.In == "body" {
+			return parameter.Schema
+		}
+	}
+	return nil
+}
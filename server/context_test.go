@@ -0,0 +1,101 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/place1/openapi-mock-server/generator"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestContextBodyIsReadableAfterValidation(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := generator.NewStubGenerator("../petstore.yaml", generator.StubGeneratorOptions{})
+	require.NoError(err)
+
+	var body []byte
+	var ctx *RequestContext
+	handler := requestContext(validationMiddleware(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body, err = ioutil.ReadAll(req.Body)
+		require.NoError(err)
+		ctx = GetRequestContext(req)
+	})), stub, 0)
+
+	req := httptest.NewRequest("POST", "/v1/pets", strings.NewReader(`{"name": "rex"}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Equal(`{"name": "rex"}`, string(body))
+	require.NotNil(ctx)
+	require.Equal(`{"name": "rex"}`, string(ctx.Body))
+	require.Equal(map[string]interface{}{"name": "rex"}, ctx.ParsedBody)
+	require.Equal("/v1/pets", ctx.Route.Path)
+}
+
+func TestRequestContextBodyTooLarge(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := generator.NewStubGenerator("../petstore.yaml", generator.StubGeneratorOptions{})
+	require.NoError(err)
+
+	called := false
+	handler := requestContext(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		called = true
+	}), stub, 8)
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("POST", "/v1/pets", strings.NewReader(`{"name": "rex"}`)))
+	require.Equal(http.StatusRequestEntityTooLarge, res.Code)
+	require.False(called)
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("POST", "/v1/pets", strings.NewReader(`{}`)))
+	require.Equal(http.StatusOK, res.Code)
+	require.True(called)
+}
+
+func TestRequestContextUsesOneSnapshot(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "overlay")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	overlay := filepath.Join(dir, "overlay.yaml")
+	writeOverlay := func(content string) {
+		require.NoError(ioutil.WriteFile(overlay, []byte(`
+paths:
+  /v1/pets:
+    get:
+      responses:
+        200:
+          raw: true
+          content: `+content+`
+`), 0644))
+	}
+	writeOverlay("first")
+
+	stub, err := generator.NewStubGenerator("../petstore.yaml", generator.StubGeneratorOptions{Overlay: overlay})
+	require.NoError(err)
+
+	// the spec and overlay are reloaded after the request has started
+	handler := requestContext(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		writeOverlay("second")
+		require.NoError(stub.Reload())
+		createHandler(stub).ServeHTTP(res, req)
+	}), stub, 0)
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/v1/pets", nil))
+	require.Equal("first", res.Body.String())
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/v1/pets", nil))
+	require.Equal("second", res.Body.String())
+}
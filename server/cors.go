@@ -0,0 +1,121 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/place1/openapi-mock-server/generator"
+)
+
+// CORSOptions configures cross origin resource sharing
+type CORSOptions struct {
+	// AllowedOrigins that may make cross origin requests.
+	// "*" allows any origin and is the default.
+	AllowedOrigins []string
+	// AllowedHeaders that may be sent in cross origin requests.
+	// Defaults to allowing whatever headers the preflight requests.
+	AllowedHeaders []string
+	// ExposedHeaders that browsers may read from cross origin responses
+	ExposedHeaders []string
+	// AllowCredentials lets cross origin requests include cookies
+	// and authorization headers. The request's origin is echoed
+	// back instead of "*" when this is enabled.
+	AllowCredentials bool
+	// MaxAge is how many seconds a preflight response may be cached.
+	// Zero omits the header.
+	MaxAge int
+}
+
+func (options CORSOptions) allowsOrigin(origin string) bool {
+	if len(options.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, allowed := range options.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (options CORSOptions) allowsAnyOrigin() bool {
+	if len(options.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, allowed := range options.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// cors adds CORS headers to responses for allowed origins
+// and answers preflight requests using the methods defined on
+// the matching path in the spec
+func cors(handler http.Handler, stub *generator.StubGenerator, options CORSOptions) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		if origin == "" {
+			handler.ServeHTTP(res, req)
+			return
+		}
+
+		preflight := req.Method == "OPTIONS" && req.Header.Get("Access-Control-Request-Method") != ""
+
+		if !options.allowsOrigin(origin) {
+			if preflight {
+				http.Error(res, "origin not allowed", http.StatusForbidden)
+				return
+			}
+			handler.ServeHTTP(res, req)
+			return
+		}
+
+		if options.allowsAnyOrigin() && !options.AllowCredentials {
+			res.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			res.Header().Set("Access-Control-Allow-Origin", origin)
+			res.Header().Add("Vary", "Origin")
+		}
+		if options.AllowCredentials {
+			res.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if len(options.ExposedHeaders) != 0 {
+				res.Header().Set("Access-Control-Expose-Headers", strings.Join(options.ExposedHeaders, ", "))
+			}
+			handler.ServeHTTP(res, req)
+			return
+		}
+
+		route, err := requestStub(req, stub).FindPathItem(req.URL.Path)
+		if err != nil {
+			http.Error(res, "unknown path", http.StatusNotFound)
+			return
+		}
+
+		methods := []string{}
+		for method := range generator.PathItemOperations(route.PathItem) {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		res.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+		if len(options.AllowedHeaders) != 0 {
+			res.Header().Set("Access-Control-Allow-Headers", strings.Join(options.AllowedHeaders, ", "))
+		} else if requested := req.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			res.Header().Set("Access-Control-Allow-Headers", requested)
+			res.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if options.MaxAge > 0 {
+			res.Header().Set("Access-Control-Max-Age", strconv.Itoa(options.MaxAge))
+		}
+
+		res.WriteHeader(http.StatusNoContent)
+	})
+}
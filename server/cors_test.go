@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/place1/openapi-mock-server/generator"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := generator.NewStubGenerator("../petstore.yaml", generator.StubGeneratorOptions{})
+	require.NoError(err)
+
+	handler := cors(http.NotFoundHandler(), stub, CORSOptions{
+		AllowedOrigins:   []string{"http://localhost:3000"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})
+
+	req := httptest.NewRequest("OPTIONS", "/v1/pets", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	require.Equal(http.StatusNoContent, res.Code)
+	require.Equal("http://localhost:3000", res.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal("true", res.Header().Get("Access-Control-Allow-Credentials"))
+	require.Equal("GET, POST", res.Header().Get("Access-Control-Allow-Methods"))
+	require.Equal("Content-Type", res.Header().Get("Access-Control-Allow-Headers"))
+	require.Equal("600", res.Header().Get("Access-Control-Max-Age"))
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := generator.NewStubGenerator("../petstore.yaml", generator.StubGeneratorOptions{})
+	require.NoError(err)
+
+	handler := cors(http.NotFoundHandler(), stub, CORSOptions{
+		AllowedOrigins: []string{"http://localhost:3000"},
+	})
+
+	req := httptest.NewRequest("OPTIONS", "/v1/pets", nil)
+	req.Header.Set("Origin", "http://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	require.Equal(http.StatusForbidden, res.Code)
+	require.Empty(res.Header().Get("Access-Control-Allow-Origin"))
+}
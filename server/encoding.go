@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/place1/openapi-mock-server/generator"
+
+	"github.com/go-openapi/spec"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Encoder serializes stubbed data into a response body.
+// The schema that the data was generated from is provided
+// for formats that need more information than the data itself.
+type Encoder func(w io.Writer, data interface{}, schema *spec.Schema) error
+
+// EncoderFor returns the Encoder that serializes the given media type
+func EncoderFor(mediaType string) (Encoder, error) {
+	parsed, err := generator.ParseMediaType(mediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case parsed.Subtype == "json" || parsed.Suffix() == "json":
+		return encodeJSON, nil
+	case parsed.Subtype == "xml" || parsed.Suffix() == "xml":
+		return encodeXML, nil
+	case parsed.Subtype == "yaml" || parsed.Subtype == "x-yaml" || parsed.Suffix() == "yaml":
+		return encodeYAML, nil
+	case parsed.Subtype == "csv":
+		return encodeCSV, nil
+	case parsed.Type == "text":
+		return encodeText, nil
+	}
+
+	return nil, fmt.Errorf("no encoder for media type %v", mediaType)
+}
+
+func encodeJSON(w io.Writer, data interface{}, schema *spec.Schema) error {
+	return json.NewEncoder(w).Encode(data)
+}
+
+func encodeYAML(w io.Writer, data interface{}, schema *spec.Schema) error {
+	return yaml.NewEncoder(w).Encode(data)
+}
+
+// encodeText writes primitives as is and falls
+// back to JSON for objects and arrays
+func encodeText(w io.Writer, data interface{}, schema *spec.Schema) error {
+	switch data.(type) {
+	case map[string]interface{}, []interface{}:
+		return encodeJSON(w, data, schema)
+	}
+	_, err := fmt.Fprint(w, data)
+	return err
+}
+
+// encodeCSV writes an array of objects as rows with a header
+// row containing every property name. A single object is written as
+// a single row and primitives are written as single column rows.
+// Nested objects and arrays are written as JSON in their cell.
+func encodeCSV(w io.Writer, data interface{}, schema *spec.Schema) error {
+	rows, ok := data.([]interface{})
+	if !ok {
+		rows = []interface{}{data}
+	}
+
+	columns := []string{}
+	seen := map[string]bool{}
+	for _, row := range rows {
+		if obj, ok := row.(map[string]interface{}); ok {
+			for key := range obj {
+				if !seen[key] {
+					seen[key] = true
+					columns = append(columns, key)
+				}
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	writer := csv.NewWriter(w)
+	if len(columns) != 0 {
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		record := []string{}
+		if obj, ok := row.(map[string]interface{}); ok {
+			for _, column := range columns {
+				record = append(record, csvCell(obj[column]))
+			}
+		} else {
+			record = append(record, csvCell(row))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvCell(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		content, _ := json.Marshal(value)
+		return string(content)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/place1/openapi-mock-server/generator"
+
+	"github.com/stretchr/testify/require"
+)
+
+func serveEncoding(t *testing.T, path string, accept string) *httptest.ResponseRecorder {
+	stub, err := generator.NewStubGenerator("testdata/encoding.yaml", generator.StubGeneratorOptions{})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Accept", accept)
+	res := httptest.NewRecorder()
+	OpenAPIMockServer(stub, &Options{}).Handler.ServeHTTP(res, req)
+	return res
+}
+
+func TestEncodeNegotiatedMediaType(t *testing.T) {
+	require := require.New(t)
+
+	cases := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", "application/json", "{\"good\":true,\"name\":\"rex\"}\n"},
+		{"application/json", "application/json", "{\"good\":true,\"name\":\"rex\"}\n"},
+		{"application/x-yaml", "application/x-yaml", "good: true\nname: rex\n"},
+		{"text/csv", "text/csv", "good,name\ntrue,rex\n"},
+		{"text/plain", "text/plain", "{\"good\":true,\"name\":\"rex\"}\n"},
+		{"text/csv;q=0.5, application/x-yaml", "application/x-yaml", "good: true\nname: rex\n"},
+	}
+
+	for _, c := range cases {
+		res := serveEncoding(t, "/v1/pet", c.accept)
+		require.Equal(http.StatusOK, res.Code, c.accept)
+		require.Equal(c.contentType, res.Header().Get("Content-Type"), c.accept)
+		require.Equal(c.body, res.Body.String(), c.accept)
+	}
+}
+
+func TestEncodeTextPrimitive(t *testing.T) {
+	require := require.New(t)
+
+	res := serveEncoding(t, "/v1/name", "text/*")
+	require.Equal(http.StatusOK, res.Code)
+	require.Equal("text/plain", res.Header().Get("Content-Type"))
+	require.Equal("rex", res.Body.String())
+}
+
+func TestEncodeNotAcceptable(t *testing.T) {
+	require := require.New(t)
+
+	res := serveEncoding(t, "/v1/pet", "application/xml")
+	require.Equal(http.StatusNotAcceptable, res.Code)
+
+	// every range is invalid so nothing is acceptable
+	res = serveEncoding(t, "/v1/pet", "json, /plain;")
+	require.Equal(http.StatusNotAcceptable, res.Code)
+}
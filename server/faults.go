@@ -0,0 +1,277 @@
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// the kinds of fault that can be injected
+const (
+	// FaultError responds with an error status code
+	FaultError = "error"
+	// FaultReset closes the connection without responding
+	FaultReset = "reset"
+	// FaultHang doesn't respond until the client gives up
+	// or the rule's duration has passed
+	FaultHang = "hang"
+	// FaultMalformed sends only the first half of the response body
+	FaultMalformed = "malformed"
+	// FaultSlow sends the response body a few bytes at a time
+	FaultSlow = "slow"
+)
+
+// FaultRule injects a fault into a share of the requests that it matches
+type FaultRule struct {
+	// Path matches the request path or the spec path template.
+	// An empty path matches every path.
+	Path string `yaml:"path"`
+	// Method matches the request method. An empty method matches every method.
+	Method string `yaml:"method"`
+	// OperationID matches the operationId of the request's operation
+	OperationID string `yaml:"operationId"`
+	// Probability is the chance (0 - 1) of injecting the fault into a matching request
+	Probability float64 `yaml:"probability"`
+	// Fault is the kind of fault: error, reset, hang, malformed or slow
+	Fault string `yaml:"fault"`
+	// Status is the status code for error faults. Defaults to 500.
+	Status int `yaml:"status"`
+	// Duration is the longest time to hang for hang faults (defaults
+	// to hanging until the client gives up) and the delay between
+	// chunks for slow faults (defaults to 100ms)
+	Duration string `yaml:"duration"`
+	// ChunkSize is the number of bytes written at a time for slow faults.
+	// Defaults to 16.
+	ChunkSize int `yaml:"chunkSize"`
+
+	duration time.Duration
+}
+
+// Faults is a set of fault rules that can be enabled
+// and disabled while the server is running
+type Faults struct {
+	Enabled bool        `yaml:"enabled"`
+	Rules   []FaultRule `yaml:"rules"`
+
+	mutex sync.RWMutex
+}
+
+// LoadFaultsFile reads a faults.yaml file. Faults are
+// enabled unless the file says otherwise.
+func LoadFaultsFile(path string) (*Faults, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading faults file")
+	}
+
+	faults := &Faults{Enabled: true}
+	err = yaml.Unmarshal(content, faults)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshalling faults file")
+	}
+
+	for i := range faults.Rules {
+		rule := &faults.Rules[i]
+		switch rule.Fault {
+		case FaultError, FaultReset, FaultHang, FaultMalformed, FaultSlow:
+		default:
+			return nil, errors.Errorf("fault rule %v has unknown fault %q", i, rule.Fault)
+		}
+		if rule.Probability < 0 || rule.Probability > 1 {
+			return nil, errors.Errorf("fault rule %v probability must be between 0 and 1", i)
+		}
+		if rule.Duration != "" {
+			rule.duration, err = time.ParseDuration(rule.Duration)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing duration of fault rule %v", i)
+			}
+		}
+	}
+
+	return faults, nil
+}
+
+// IsEnabled reports whether faults are being injected
+func (faults *Faults) IsEnabled() bool {
+	faults.mutex.RLock()
+	defer faults.mutex.RUnlock()
+	return faults.Enabled
+}
+
+// SetEnabled turns fault injection on or off
+func (faults *Faults) SetEnabled(enabled bool) {
+	faults.mutex.Lock()
+	defer faults.mutex.Unlock()
+	faults.Enabled = enabled
+}
+
+// pick returns the first matching rule that passes it's
+// probability check or nil if no fault should be injected
+func (faults *Faults) pick(req *http.Request) *FaultRule {
+	if !faults.IsEnabled() {
+		return nil
+	}
+
+	var route string
+	var operationID string
+	if ctx := GetRequestContext(req); ctx != nil && ctx.Route != nil {
+		route = ctx.Route.Path
+		operationID = ctx.Route.Operation.ID
+	}
+
+	for i := range faults.Rules {
+		rule := &faults.Rules[i]
+		if rule.Path != "" && rule.Path != req.URL.Path && rule.Path != route {
+			continue
+		}
+		if rule.Method != "" && !strings.EqualFold(rule.Method, req.Method) {
+			continue
+		}
+		if rule.OperationID != "" && rule.OperationID != operationID {
+			continue
+		}
+		if rand.Float64() < rule.Probability {
+			return rule
+		}
+	}
+	return nil
+}
+
+// injectFaults injects failures into responses using
+// the first matching fault rule
+func injectFaults(handler http.Handler, faults *Faults) http.Handler {
+	if faults == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		rule := faults.pick(req)
+		if rule == nil {
+			handler.ServeHTTP(res, req)
+			return
+		}
+
+		switch rule.Fault {
+		case FaultError:
+			status := rule.Status
+			if status == 0 {
+				status = http.StatusInternalServerError
+			}
+			http.Error(res, "injected fault", status)
+
+		case FaultReset:
+			resetConnection(res)
+
+		case FaultHang:
+			var timeout <-chan time.Time
+			if rule.duration > 0 {
+				timeout = time.After(rule.duration)
+			}
+			select {
+			case <-req.Context().Done():
+			case <-timeout:
+				resetConnection(res)
+			}
+
+		case FaultMalformed:
+			recorder := newBufferedResponseWriter()
+			handler.ServeHTTP(recorder, req)
+			body := recorder.body.Bytes()
+			copyHeaders(res.Header(), recorder.Header())
+			res.Header().Del("Content-Length")
+			res.WriteHeader(recorder.status)
+			res.Write(body[:len(body)/2])
+
+		case FaultSlow:
+			recorder := newBufferedResponseWriter()
+			handler.ServeHTTP(recorder, req)
+			copyHeaders(res.Header(), recorder.Header())
+			res.Header().Set("Content-Length", strconv.Itoa(recorder.body.Len()))
+			res.WriteHeader(recorder.status)
+			slowWrite(res, req, recorder.body.Bytes(), rule)
+		}
+	})
+}
+
+// resetConnection closes the client's connection without
+// writing a response
+func resetConnection(res http.ResponseWriter) {
+	hijacker, ok := res.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	conn.Close()
+}
+
+// slowWrite writes the body in small chunks with
+// a delay before each chunk
+func slowWrite(res http.ResponseWriter, req *http.Request, body []byte, rule *FaultRule) {
+	chunkSize := rule.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 16
+	}
+	delay := rule.duration
+	if delay <= 0 {
+		delay = 100 * time.Millisecond
+	}
+
+	flusher, _ := res.(http.Flusher)
+	for len(body) > 0 {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-time.After(delay):
+		}
+
+		n := chunkSize
+		if n > len(body) {
+			n = len(body)
+		}
+		res.Write(body[:n])
+		if flusher != nil {
+			flusher.Flush()
+		}
+		body = body[n:]
+	}
+}
+
+// bufferedResponseWriter captures a response so
+// that it can be altered before being sent
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: http.Header{}, status: http.StatusOK}
+}
+
+func (writer *bufferedResponseWriter) Header() http.Header {
+	return writer.header
+}
+
+func (writer *bufferedResponseWriter) WriteHeader(status int) {
+	writer.status = status
+}
+
+func (writer *bufferedResponseWriter) Write(content []byte) (int, error) {
+	return writer.body.Write(content)
+}
+
+func copyHeaders(dst http.Header, src http.Header) {
+	for name, values := range src {
+		dst[name] = values
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFaultsFile(t *testing.T) {
+	require := require.New(t)
+
+	faults, err := LoadFaultsFile("../faults.yaml")
+	require.NoError(err)
+
+	require.True(faults.IsEnabled())
+	require.Len(faults.Rules, 3)
+}
+
+func TestInjectErrorFault(t *testing.T) {
+	require := require.New(t)
+
+	faults := &Faults{
+		Enabled: true,
+		Rules:   []FaultRule{{Path: "/v1/pets", Probability: 1, Fault: FaultError, Status: 503}},
+	}
+	handler := injectFaults(okHandler(), faults)
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/v1/pets", nil))
+	require.Equal(http.StatusServiceUnavailable, res.Code)
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/v1/other", nil))
+	require.Equal(http.StatusOK, res.Code)
+}
+
+func TestInjectMalformedFault(t *testing.T) {
+	require := require.New(t)
+
+	faults := &Faults{
+		Enabled: true,
+		Rules:   []FaultRule{{Probability: 1, Fault: FaultMalformed}},
+	}
+	handler := injectFaults(okHandler(), faults)
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/v1/pets", nil))
+
+	require.Equal(http.StatusOK, res.Code)
+	require.Equal(`{"name"`, res.Body.String())
+}
+
+func TestFaultsEndpointTogglesFaults(t *testing.T) {
+	require := require.New(t)
+
+	faults := &Faults{
+		Enabled: true,
+		Rules:   []FaultRule{{Probability: 1, Fault: FaultError}},
+	}
+	options := &Options{Faults: faults}
+	handler := admin(injectFaults(okHandler(), faults), nil, options)
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("PUT", "/__mock/faults", strings.NewReader(`{"enabled": false}`)))
+	require.Equal(http.StatusOK, res.Code)
+
+	status := faultsStatus{}
+	require.NoError(json.NewDecoder(res.Body).Decode(&status))
+	require.False(status.Enabled)
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/v1/pets", nil))
+	require.Equal(http.StatusOK, res.Code)
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte(`{"name":"rex"}`))
+	})
+}
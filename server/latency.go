@@ -0,0 +1,48 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/place1/openapi-mock-server/generator"
+
+	"github.com/pkg/errors"
+)
+
+// latency delays responses by the latency of the operation
+// from the overlay or spec, falling back to the global latency
+func latency(handler http.Handler, stub *generator.StubGenerator, global *generator.Latency) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		latency := global
+
+		if ctx := GetRequestContext(req); ctx != nil && ctx.Route != nil {
+			operationLatency, err := requestStub(req, stub).FindLatency(req.URL.Path, req.Method)
+			if err != nil {
+				log.Println(errors.Wrap(err, "finding operation latency"))
+			} else if operationLatency != nil {
+				latency = operationLatency
+			}
+		}
+
+		if !sleep(req, latency) {
+			return
+		}
+
+		handler.ServeHTTP(res, req)
+	})
+}
+
+// sleep waits for a duration from the latency. It returns
+// false if the request was cancelled while waiting.
+func sleep(req *http.Request, latency *generator.Latency) bool {
+	if latency == nil {
+		return true
+	}
+	select {
+	case <-time.After(latency.Duration()):
+		return true
+	case <-req.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/place1/openapi-mock-server/generator"
+
+	"github.com/go-openapi/spec"
+)
+
+// autoMethods answers HEAD and OPTIONS requests for paths that
+// don't define those operations in the spec. HEAD is served as a GET
+// without a body and OPTIONS responds with an Allow header. When
+// they're disabled the response is a 405 with an Allow header.
+func autoMethods(handler http.Handler, stub *generator.StubGenerator, autoHead bool, autoOptions bool) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != "HEAD" && req.Method != "OPTIONS" {
+			handler.ServeHTTP(res, req)
+			return
+		}
+
+		stub := requestStub(req, stub)
+		route, err := stub.FindPathItem(req.URL.Path)
+		if err != nil {
+			handler.ServeHTTP(res, req)
+			return
+		}
+		operations := generator.PathItemOperations(route.PathItem)
+		if _, ok := operations[req.Method]; ok {
+			handler.ServeHTTP(res, req)
+			return
+		}
+
+		switch {
+		case req.Method == "HEAD" && autoHead && operations["GET"] != nil:
+			get := req.WithContext(req.Context())
+			get.Method = "GET"
+			if ctx := GetRequestContext(req); ctx != nil {
+				ctx.Route, _ = stub.FindRoute(req.URL.Path, "GET")
+			}
+			handler.ServeHTTP(headResponseWriter{res}, get)
+
+		case req.Method == "OPTIONS" && autoOptions:
+			res.Header().Set("Allow", strings.Join(allowedMethods(route.PathItem, autoHead, autoOptions), ", "))
+			res.WriteHeader(http.StatusNoContent)
+
+		default:
+			// the path exists but doesn't allow the method
+			res.Header().Set("Allow", strings.Join(allowedMethods(route.PathItem, autoHead, autoOptions), ", "))
+			http.Error(res, fmt.Sprintf("method %v not allowed", req.Method), http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// allowedMethods lists the methods that can be used on a
+// path, including the methods that autoMethods provides
+func allowedMethods(pathItem spec.PathItem, autoHead bool, autoOptions bool) []string {
+	operations := generator.PathItemOperations(pathItem)
+	if autoHead && operations["GET"] != nil {
+		operations["HEAD"] = operations["GET"]
+	}
+	if autoOptions {
+		operations["OPTIONS"] = &spec.Operation{}
+	}
+
+	methods := []string{}
+	for method := range operations {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// headResponseWriter discards the body of a response
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (writer headResponseWriter) Write(content []byte) (int, error) {
+	return len(content), nil
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/place1/openapi-mock-server/generator"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoHead(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := generator.NewStubGenerator("../petstore.yaml", generator.StubGeneratorOptions{})
+	require.NoError(err)
+
+	handler := autoMethods(createHandler(stub), stub, true, true)
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("HEAD", "/v1/pets", nil))
+
+	require.Equal(http.StatusOK, res.Code)
+	require.Equal("application/json", res.Header().Get("Content-Type"))
+	require.Empty(res.Body.String())
+}
+
+func TestAutoOptions(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := generator.NewStubGenerator("../petstore.yaml", generator.StubGeneratorOptions{})
+	require.NoError(err)
+
+	handler := autoMethods(createHandler(stub), stub, true, true)
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("OPTIONS", "/v1/pets", nil))
+
+	require.Equal(http.StatusNoContent, res.Code)
+	require.Equal("GET, HEAD, OPTIONS, POST", res.Header().Get("Allow"))
+}
+
+func TestAutoMethodsDisabled(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := generator.NewStubGenerator("../petstore.yaml", generator.StubGeneratorOptions{})
+	require.NoError(err)
+
+	handler := autoMethods(createHandler(stub), stub, false, false)
+
+	for _, method := range []string{"HEAD", "OPTIONS"} {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(method, "/v1/pets", nil))
+
+		require.Equal(http.StatusMethodNotAllowed, res.Code, method)
+		require.Equal("GET, POST", res.Header().Get("Allow"), method)
+	}
+}
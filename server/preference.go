@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/place1/openapi-mock-server/generator"
+)
+
+// query parameters that can be used instead of
+// the Prefer header to choose a response
+const (
+	codeQueryParam    = "__code"
+	exampleQueryParam = "__example"
+)
+
+// ParsePreference reads the client's choice of response from
+// the Prefer header (i.e. "Prefer: code=404, example=application/json")
+// or the __code and __example query parameters.
+// Query parameters take precedence over the header.
+func ParsePreference(req *http.Request) generator.ResponsePreference {
+	preference := generator.ResponsePreference{}
+
+	for _, header := range req.Header["Prefer"] {
+		for _, item := range strings.Split(header, ",") {
+			// preferences can have parameters after a ";"
+			// which we don't use
+			item = strings.SplitN(item, ";", 2)[0]
+			parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			value := strings.Trim(strings.TrimSpace(parts[1]), `"`)
+			switch strings.ToLower(strings.TrimSpace(parts[0])) {
+			case "code", "status":
+				if code, err := strconv.Atoi(value); err == nil {
+					preference.StatusCode = code
+				}
+			case "example":
+				preference.Example = value
+			}
+		}
+	}
+
+	query := req.URL.Query()
+	if code, err := strconv.Atoi(query.Get(codeQueryParam)); err == nil {
+		preference.StatusCode = code
+	}
+	if example := query.Get(exampleQueryParam); example != "" {
+		preference.Example = example
+	}
+
+	return preference
+}
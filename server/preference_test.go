@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/place1/openapi-mock-server/generator"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePreference(t *testing.T) {
+	require := require.New(t)
+
+	req := httptest.NewRequest("GET", "/pets", nil)
+	req.Header.Set("Prefer", `code=404, example="application/json"`)
+
+	require.Equal(generator.ResponsePreference{StatusCode: 404, Example: "application/json"}, ParsePreference(req))
+}
+
+func TestParsePreferenceQueryOverridesHeader(t *testing.T) {
+	require := require.New(t)
+
+	req := httptest.NewRequest("GET", "/pets?__code=500", nil)
+	req.Header.Set("Prefer", "code=404")
+
+	require.Equal(500, ParsePreference(req).StatusCode)
+}
+
+func TestUnknownPreferenceIsABadRequest(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := generator.NewStubGenerator("testdata/encoding.yaml", generator.StubGeneratorOptions{})
+	require.NoError(err)
+	handler := OpenAPIMockServer(stub, &Options{}).Handler
+
+	for _, prefer := range []string{"code=418", "example=application/xml"} {
+		req := httptest.NewRequest("GET", "/v1/pet", nil)
+		req.Header.Set("Prefer", prefer)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		require.Equal(http.StatusBadRequest, res.Code, prefer)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/place1/openapi-mock-server/generator"
+)
+
+// panicResponse is the body sent to the client when
+// stubbing a response panics
+type panicResponse struct {
+	Error      string `json:"error"`
+	Operation  string `json:"operation,omitempty"`
+	SchemaPath string `json:"schemaPath,omitempty"`
+	Message    string `json:"message"`
+}
+
+// recovery turns a panic while handling a request into a 500
+// response that names the operation and schema that failed
+// rather than dropping the connection. It adds an empty
+// RequestContext to the request for requestContext to fill in
+// so that the operation is known when a later handler panics.
+func recovery(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if GetRequestContext(req) == nil {
+			req = req.WithContext(context.WithValue(req.Context(), requestContextKey{}, &RequestContext{}))
+		}
+
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				// the handler wants the connection dropped
+				panic(r)
+			}
+
+			body := panicResponse{
+				Error:   "stub server panic",
+				Message: fmt.Sprint(r),
+			}
+			if ctx := GetRequestContext(req); ctx != nil && ctx.Route != nil {
+				body.Operation = ctx.Route.Operation.ID
+			}
+			if schemaErr, ok := r.(*generator.SchemaError); ok {
+				body.SchemaPath = schemaErr.Path
+				body.Message = schemaErr.Message
+			}
+
+			log.Printf("recovered from panic in %v %v: operation %q schema path %q: %v\n%s", req.Method, req.URL.Path, body.Operation, body.SchemaPath, body.Message, debug.Stack())
+
+			res.Header().Set("Content-Type", "application/json")
+			res.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(res).Encode(body)
+		}()
+		handler.ServeHTTP(res, req)
+	})
+}
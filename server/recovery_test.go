@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/place1/openapi-mock-server/generator"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecovery(t *testing.T) {
+	require := require.New(t)
+
+	handler := recovery(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		panic(&generator.SchemaError{Path: "#/properties/data", Message: "unknown schema type"})
+	}))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/pets", nil))
+
+	require.Equal(http.StatusInternalServerError, res.Code)
+	require.JSONEq(`{"error": "stub server panic", "schemaPath": "#/properties/data", "message": "unknown schema type"}`, res.Body.String())
+}
+
+func TestRecoveryOutsideRequestContext(t *testing.T) {
+	require := require.New(t)
+
+	stub, err := generator.NewStubGenerator("../petstore.yaml", generator.StubGeneratorOptions{})
+	require.NoError(err)
+
+	handler := recovery(requestContext(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	}), stub, 0))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/v1/pets", nil))
+
+	require.Equal(http.StatusInternalServerError, res.Code)
+	require.JSONEq(`{"error": "stub server panic", "operation": "listPets", "message": "boom"}`, res.Body.String())
+}
@@ -1,7 +1,7 @@
 package server
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,6 +15,21 @@ import (
 type Options struct {
 	Host string
 	Port int
+	// MaxBodySize is the largest request body in bytes that
+	// the server will accept. Defaults to DefaultMaxBodySize.
+	MaxBodySize int64
+	CORS        CORSOptions
+	// AutoHead serves HEAD requests using the GET operation
+	// for paths that don't define a HEAD operation
+	AutoHead bool
+	// AutoOptions answers OPTIONS requests with an Allow header
+	// for paths that don't define an OPTIONS operation
+	AutoOptions bool
+	// Latency delays every response unless the operation
+	// has it's own latency. nil means no delay.
+	Latency *generator.Latency
+	// Faults are injected into responses. nil means no faults.
+	Faults *Faults
 }
 
 // OpenAPIMockServer returns an http.Server that pretends to be the API
@@ -22,9 +37,15 @@ type Options struct {
 func OpenAPIMockServer(generator *generator.StubGenerator, options *Options) *http.Server {
 
 	handler := createHandler(generator)
-	handler = cors(handler)
+	handler = autoMethods(handler, generator, options.AutoHead, options.AutoOptions)
+	handler = injectFaults(handler, options.Faults)
+	handler = cors(handler, generator, options.CORS)
 	handler = requestLogger(handler)
-	handler = validationMiddleware(handler, generator)
+	handler = latency(handler, generator, options.Latency)
+	handler = validationMiddleware(handler)
+	handler = requestContext(handler, generator, options.MaxBodySize)
+	handler = admin(handler, generator, options)
+	handler = recovery(handler)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf("%v:%v", options.Host, options.Port),
@@ -34,41 +55,102 @@ func OpenAPIMockServer(generator *generator.StubGenerator, options *Options) *ht
 	return server
 }
 
-func createHandler(generator *generator.StubGenerator) http.Handler {
+func createHandler(stub *generator.StubGenerator) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
-		response, err := generator.StubResponse(req.URL.Path, req.Method)
+		request := generator.Request{
+			Path:       req.URL.Path,
+			Method:     req.Method,
+			Accept:     req.Header.Get("Accept"),
+			Preference: ParsePreference(req),
+			Query:      req.URL.Query(),
+			Header:     req.Header,
+		}
+		if ctx := GetRequestContext(req); ctx != nil {
+			request.Body = ctx.ParsedBody
+			if ctx.Route != nil {
+				request.Params = ctx.Route.Params
+			}
+		}
+
+		response, err := requestStub(req, stub).StubResponse(request)
+		if errors.Cause(err) == generator.ErrNotAcceptable {
+			log.Println(errors.Wrap(err, "negotiating response content type"))
+			http.Error(res, "not acceptable - check the logs", http.StatusNotAcceptable)
+			return
+		}
+		if errors.Cause(err) == generator.ErrNoPreferredResponse {
+			log.Println(errors.Wrap(err, "finding preferred response"))
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			log.Println(errors.Wrap(err, "unable to stub response"))
 			http.Error(res, "stub server error - check the logs", http.StatusInternalServerError)
 			return
 		}
 
-		res.Header().Set("Content-Type", "application/json")
-		err = json.NewEncoder(res).Encode(response)
+		if !sleep(req, response.Delay) {
+			return
+		}
+
+		for name, value := range response.Headers {
+			res.Header().Set(name, value)
+		}
+
+		if response.MediaType == "" {
+			res.WriteHeader(response.StatusCode)
+			return
+		}
+
+		if response.Raw {
+			res.Header().Set("Content-Type", response.MediaType)
+			res.WriteHeader(response.StatusCode)
+			res.Write(response.Body.([]byte))
+			return
+		}
+
+		encode, err := EncoderFor(response.MediaType)
+		if err != nil {
+			log.Println(errors.Wrap(err, "finding response encoder"))
+			http.Error(res, "stub server error - check the logs", http.StatusInternalServerError)
+			return
+		}
+
+		var body bytes.Buffer
+		err = encode(&body, response.Body, response.Schema)
 		if err != nil {
 			log.Println(errors.Wrap(err, "unable to serialize generated response stub"))
 			http.Error(res, "stub server error - check the logs", http.StatusInternalServerError)
 			return
 		}
+
+		res.Header().Set("Content-Type", response.MediaType)
+		res.WriteHeader(response.StatusCode)
+		res.Write(body.Bytes())
 	})
 }
 
-func validationMiddleware(handler http.Handler, generator *generator.StubGenerator) http.Handler {
+func validationMiddleware(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		switch req.Method {
 		case "POST", "PUT", "PATCH":
-			operation, err := generator.FindOperation(req.URL.Path, req.Method)
-			if err != nil {
-				log.Println(errors.Wrap(err, "finding operation schema for path and method"))
+			ctx := GetRequestContext(req)
+			if ctx == nil {
+				// the request wasn't buffered so there's nothing to validate
+				break
+			}
+			if ctx.Route == nil {
+				log.Printf("finding operation schema for path and method: unknown operation %v %v", req.Method, req.URL.Path)
 				break
 			}
+			operation := ctx.Route.Operation
 
-			err = ValidateConsumes(*operation, *req)
+			err := ValidateConsumes(*operation, *req)
 			if err != nil {
 				log.Println(errors.Wrap(err, "validating content type header"))
 			}
 
-			err = ValidateParameters(*operation, *req)
+			err = ValidateParameters(*operation, *req, ctx.Body)
 			if err != nil {
 				log.Println(errors.Wrap(err, "validating parameters"))
 			}
@@ -89,10 +171,3 @@ func errorLogger(handler http.Handler) http.Handler {
 		handler.ServeHTTP(res, req)
 	})
 }
-
-func cors(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
-		res.Header().Set("Access-Control-Allow-Origin", "*")
-		handler.ServeHTTP(res, req)
-	})
-}
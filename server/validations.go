@@ -1,10 +1,16 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/place1/openapi-mock-server/generator"
 
 	"github.com/go-openapi/spec"
 	"github.com/go-openapi/strfmt"
@@ -12,6 +18,8 @@ import (
 	"github.com/pkg/errors"
 )
 
+// ValidateConsumes validates the request's content type header
+// against the media types that the operation consumes
 func ValidateConsumes(operation spec.Operation, req http.Request) error {
 	contentType := req.Header["Content-Type"]
 
@@ -26,40 +34,61 @@ func ValidateConsumes(operation spec.Operation, req http.Request) error {
 
 	// if the spec says there a content type consumed
 	// validate that the request content type is correct
-	for _, consumes := range operation.Consumes {
-		for _, item := range contentType {
-			if item == consumes {
-				return nil
-			}
+	for _, item := range contentType {
+		if generator.MediaTypeMatchesAny(item, operation.Consumes) {
+			return nil
 		}
 	}
 
 	return fmt.Errorf("operation %v expected to consume %v but found a content type of %v", operation.ID, operation.Consumes, contentType)
 }
 
-func ValidateParameters(operation spec.Operation, req http.Request) error {
+// ValidateParameters validates the request and it's buffered
+// body against the operation's parameters
+func ValidateParameters(operation spec.Operation, req http.Request, body []byte) error {
+	var form url.Values
+	var err error
+
 	for _, parameter := range operation.Parameters {
 		switch parameter.In {
 		case "body":
 			// TODO: confirm that a swagger spec can only have 1 body param
-			body, err := ioutil.ReadAll(req.Body)
-			if err != nil {
-				return errors.Wrap(err, "reading response body")
-			}
-
-			if len(body) == 0 && parameter.Required {
-				return fmt.Errorf("missing required body parameter")
+			if len(body) == 0 {
+				if parameter.Required {
+					return fmt.Errorf("missing required body parameter")
+				}
+				break
 			}
 
 			// parse the request body
-			jsonValue := map[string]interface{}{}
-			err = json.Unmarshal(body, &jsonValue)
+			value, err := DecodeBody(req.Header.Get("Content-Type"), body, parameter.Schema)
 			if err != nil {
 				return errors.Wrap(err, "decoding request body")
 			}
 
 			// run the validation
-			err = validate.AgainstSchema(parameter.Schema, jsonValue, strfmt.Default)
+			err = validate.AgainstSchema(parameter.Schema, value, strfmt.Default)
+			if err != nil {
+				return errors.Wrap(err, "validating parameter")
+			}
+
+		case "formData":
+			if form == nil {
+				form, err = decodeForm(req.Header.Get("Content-Type"), body)
+				if err != nil {
+					return errors.Wrap(err, "decoding form body")
+				}
+			}
+
+			values, ok := form[parameter.Name]
+			if !ok || len(values) == 0 {
+				if parameter.Required {
+					return fmt.Errorf("missing required form parameter %v", parameter.Name)
+				}
+				break
+			}
+
+			err := validateSimpleParameter(parameter, values)
 			if err != nil {
 				return errors.Wrap(err, "validating parameter")
 			}
@@ -80,3 +109,141 @@ func ValidateParameters(operation spec.Operation, req http.Request) error {
 
 	return nil
 }
+
+// DecodeBody parses a request body into a generic value using the
+// decoder for the given content type. JSON is assumed when the content
+// type is missing or unknown. The schema is used to coerce formats that
+// don't carry type information (forms and xml) into the expected types.
+func DecodeBody(contentType string, body []byte, schema *spec.Schema) (interface{}, error) {
+	mediaType, _ := generator.ParseMediaType(contentType)
+
+	switch {
+	case mediaType.String() == "application/x-www-form-urlencoded", mediaType.String() == "multipart/form-data":
+		form, err := decodeForm(contentType, body)
+		if err != nil {
+			return nil, err
+		}
+		obj := map[string]interface{}{}
+		for key, values := range form {
+			var propSchema *spec.Schema
+			if schema != nil {
+				if prop, ok := schema.Properties[key]; ok {
+					propSchema = &prop
+				}
+			}
+			if propSchema != nil && propSchema.Type.Contains("array") {
+				// items without a single schema are left as strings
+				itemSchema := xmlItemSchema(propSchema)
+				items := make([]interface{}, len(values))
+				for i, value := range values {
+					items[i] = coerceString(value, itemSchema)
+				}
+				obj[key] = items
+			} else if len(values) > 0 {
+				obj[key] = coerceString(values[0], propSchema)
+			}
+		}
+		return obj, nil
+
+	case mediaType.Subtype == "xml", mediaType.Suffix() == "xml":
+		return DecodeXML(body, schema)
+
+	default:
+		var value interface{}
+		err := json.Unmarshal(body, &value)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+// decodeForm parses an urlencoded or multipart request body
+// into it's form values. Multipart files are represented by
+// their filename.
+func decodeForm(contentType string, body []byte) (url.Values, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing content type")
+	}
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		return url.ParseQuery(string(body))
+
+	case "multipart/form-data":
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		form, err := reader.ReadForm(int64(len(body)))
+		if err != nil {
+			return nil, errors.Wrap(err, "reading multipart form")
+		}
+		defer form.RemoveAll()
+
+		values := url.Values(form.Value)
+		for name, files := range form.File {
+			for _, file := range files {
+				values.Add(name, file.Filename)
+			}
+		}
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("content type %v is not a form", mediaType)
+}
+
+// validateSimpleParameter validates the raw string values of a
+// non-body parameter (query, header, formData) against the
+// parameter's type definition
+func validateSimpleParameter(parameter spec.Parameter, values []string) error {
+	var value interface{}
+	if parameter.Type == "array" {
+		itemType := ""
+		if parameter.Items != nil {
+			itemType = parameter.Items.Type
+		}
+		items := make([]interface{}, len(values))
+		for i, item := range values {
+			items[i] = coerceSimpleString(item, itemType)
+		}
+		value = items
+	} else if parameter.Type == "file" {
+		// files are only checked for presence
+		return nil
+	} else {
+		value = coerceSimpleString(values[0], parameter.Type)
+	}
+
+	result := validate.NewParamValidator(&parameter, strfmt.Default).Validate(value)
+	if result != nil && !result.IsValid() {
+		return result.AsError()
+	}
+	return nil
+}
+
+// coerceString converts a string into the type described
+// by the schema. If the schema is nil or the string can't be converted
+// the string is returned as is so that validation can report the error.
+func coerceString(value string, schema *spec.Schema) interface{} {
+	if schema == nil || len(schema.Type) == 0 {
+		return value
+	}
+	return coerceSimpleString(value, schema.Type[0])
+}
+
+func coerceSimpleString(value string, schemaType string) interface{} {
+	switch schemaType {
+	case "integer":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/spec"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeBodyWithArray(t *testing.T) {
+	require := require.New(t)
+
+	value, err := DecodeBody("application/json", []byte(`[1, 2, 3]`), nil)
+	require.NoError(err)
+
+	require.Len(value, 3)
+}
+
+func TestDecodeBodyWithForm(t *testing.T) {
+	require := require.New(t)
+
+	schema := spec.Schema{
+		SchemaProps: spec.SchemaProps{
+			Type: spec.StringOrArray{"object"},
+			Properties: map[string]spec.Schema{
+				"age": *spec.Int64Property(),
+			},
+		},
+	}
+
+	value, err := DecodeBody("application/x-www-form-urlencoded", []byte("name=rex&age=3"), &schema)
+	require.NoError(err)
+
+	require.Equal(map[string]interface{}{"name": "rex", "age": int64(3)}, value)
+}
+
+func TestDecodeBodyWithFormArrayWithoutItems(t *testing.T) {
+	require := require.New(t)
+
+	tuple := spec.ArrayProperty(nil)
+	tuple.Items = &spec.SchemaOrArray{Schemas: []spec.Schema{*spec.Int64Property()}}
+	schema := spec.Schema{
+		SchemaProps: spec.SchemaProps{
+			Type: spec.StringOrArray{"object"},
+			Properties: map[string]spec.Schema{
+				"tags":  *spec.ArrayProperty(nil),
+				"tuple": *tuple,
+			},
+		},
+	}
+
+	value, err := DecodeBody("application/x-www-form-urlencoded", []byte("tags=a&tags=b&tuple=1"), &schema)
+	require.NoError(err)
+
+	require.Equal(map[string]interface{}{"tags": []interface{}{"a", "b"}, "tuple": []interface{}{"1"}}, value)
+}
+
+func TestValidateSimpleParameterArrayWithoutItems(t *testing.T) {
+	require := require.New(t)
+
+	parameter := spec.QueryParam("ids").Typed("array", "")
+
+	require.NoError(validateSimpleParameter(*parameter, []string{"1", "2"}))
+}
+
+func TestDecodeXML(t *testing.T) {
+	require := require.New(t)
+
+	schema := spec.Schema{
+		SchemaProps: spec.SchemaProps{
+			Type: spec.StringOrArray{"object"},
+			Properties: map[string]spec.Schema{
+				"id":   *spec.Int64Property().AsXMLAttribute(),
+				"name": *spec.StringProperty(),
+			},
+		},
+	}
+
+	value, err := DecodeXML([]byte(`<pet id="7"><name>rex</name></pet>`), &schema)
+	require.NoError(err)
+
+	require.Equal(map[string]interface{}{"id": int64(7), "name": "rex"}, value)
+}
+
+func TestDecodeXMLArrayWithoutItems(t *testing.T) {
+	require := require.New(t)
+
+	schema := spec.Schema{
+		SchemaProps: spec.SchemaProps{
+			Type: spec.StringOrArray{"object"},
+			Properties: map[string]spec.Schema{
+				"tag": *spec.ArrayProperty(nil),
+			},
+		},
+	}
+
+	value, err := DecodeXML([]byte(`<pet><tag>a</tag><tag>b</tag></pet>`), &schema)
+	require.NoError(err)
+
+	require.Equal(map[string]interface{}{"tag": []interface{}{"a", "b"}}, value)
+}
+
+func TestValidationMiddlewareWithoutRequestContext(t *testing.T) {
+	require := require.New(t)
+
+	handler := validationMiddleware(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusCreated)
+	}))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("POST", "/v1/pets", strings.NewReader(`{}`)))
+	require.Equal(http.StatusCreated, res.Code)
+}
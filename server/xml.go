@@ -0,0 +1,334 @@
+package server
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"github.com/pkg/errors"
+)
+
+// xmlNode is a generic representation of an xml element
+type xmlNode struct {
+	Name     string
+	Attrs    map[string]string
+	Children []*xmlNode
+	Text     string
+}
+
+// DecodeXML parses an xml document into a generic value shaped
+// by the schema. XML has no native types so the schema is used to decide
+// which elements are objects, arrays or primitives. The xml object on each
+// schema (name, attribute, wrapped) is honored.
+func DecodeXML(body []byte, schema *spec.Schema) (interface{}, error) {
+	root, err := parseXML(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing xml")
+	}
+	return xmlNodeToValue(root, schema), nil
+}
+
+func parseXML(body []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+
+	var root *xmlNode
+	stack := []*xmlNode{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{
+				Name:  token.Name.Local,
+				Attrs: map[string]string{},
+			}
+			for _, attr := range token.Attr {
+				node.Attrs[attr.Name.Local] = attr.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += string(token)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("document has no root element")
+	}
+	return root, nil
+}
+
+func xmlNodeToValue(node *xmlNode, schema *spec.Schema) interface{} {
+	if schema == nil {
+		return xmlNodeToUntypedValue(node)
+	}
+
+	if schema.Type.Contains("array") {
+		// a top level (or wrapped) array is the list of child elements
+		items := []interface{}{}
+		for _, child := range node.Children {
+			items = append(items, xmlNodeToValue(child, xmlItemSchema(schema)))
+		}
+		return items
+	}
+
+	if schema.Type.Contains("object") || len(schema.Properties) != 0 {
+		obj := map[string]interface{}{}
+		for property, propSchema := range schema.Properties {
+			propSchema := propSchema
+			name := xmlName(property, &propSchema)
+
+			if propSchema.XML != nil && propSchema.XML.Attribute {
+				if value, ok := node.Attrs[name]; ok {
+					obj[property] = coerceString(value, &propSchema)
+				}
+				continue
+			}
+
+			if propSchema.Type.Contains("array") {
+				if propSchema.XML != nil && propSchema.XML.Wrapped {
+					if wrapper := findChild(node, name); wrapper != nil {
+						obj[property] = xmlNodeToValue(wrapper, &propSchema)
+					}
+					continue
+				}
+				// unwrapped arrays repeat the item element
+				// directly inside the parent
+				itemSchema := xmlItemSchema(&propSchema)
+				itemName := name
+				if itemSchema != nil && itemSchema.XML != nil && itemSchema.XML.Name != "" {
+					itemName = itemSchema.XML.Name
+				}
+				items := []interface{}{}
+				for _, child := range node.Children {
+					if child.Name == itemName {
+						items = append(items, xmlNodeToValue(child, itemSchema))
+					}
+				}
+				if len(items) != 0 {
+					obj[property] = items
+				}
+				continue
+			}
+
+			if child := findChild(node, name); child != nil {
+				obj[property] = xmlNodeToValue(child, &propSchema)
+			}
+		}
+		return obj
+	}
+
+	return coerceString(strings.TrimSpace(node.Text), schema)
+}
+
+// xmlNodeToUntypedValue is used when there's no schema to guide
+// decoding. Elements with children become objects and leaf elements
+// become strings.
+func xmlNodeToUntypedValue(node *xmlNode) interface{} {
+	if len(node.Children) == 0 && len(node.Attrs) == 0 {
+		return strings.TrimSpace(node.Text)
+	}
+	obj := map[string]interface{}{}
+	for name, value := range node.Attrs {
+		obj[name] = value
+	}
+	for _, child := range node.Children {
+		obj[child.Name] = xmlNodeToUntypedValue(child)
+	}
+	return obj
+}
+
+func findChild(node *xmlNode, name string) *xmlNode {
+	for _, child := range node.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// xmlName returns the element (or attribute) name for a property,
+// preferring the name from the schema's xml object
+func xmlName(property string, schema *spec.Schema) string {
+	if schema.XML != nil && schema.XML.Name != "" {
+		return schema.XML.Name
+	}
+	return property
+}
+
+// encodeXML writes stubbed data as an xml document following
+// the xml object of the schema and it's properties: element names,
+// namespaces, prefixes, attributes and wrapped arrays.
+func encodeXML(w io.Writer, data interface{}, schema *spec.Schema) error {
+	name := "response"
+	if schema != nil {
+		if schema.XML != nil && schema.XML.Name != "" {
+			name = schema.XML.Name
+		} else if schema.Title != "" {
+			name = schema.Title
+		}
+	}
+
+	encoder := xml.NewEncoder(w)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if err := encodeXMLElement(encoder, name, data, schema); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+// encodeXMLElement writes data as a single element. Arrays
+// are written as a wrapper element containing their items.
+func encodeXMLElement(encoder *xml.Encoder, name string, data interface{}, schema *spec.Schema) error {
+	start := xml.StartElement{Name: xmlQualifiedName(name, schema)}
+	start.Attr = xmlNamespaceAttrs(schema)
+
+	obj, isObject := data.(map[string]interface{})
+	if isObject && schema != nil {
+		for _, property := range sortedKeys(obj) {
+			propSchema, ok := schema.Properties[property]
+			if !ok || propSchema.XML == nil || !propSchema.XML.Attribute {
+				continue
+			}
+			start.Attr = append(start.Attr, xml.Attr{
+				Name:  xmlQualifiedName(xmlName(property, &propSchema), &propSchema),
+				Value: xmlText(obj[property]),
+			})
+			start.Attr = append(start.Attr, xmlNamespaceAttrs(&propSchema)...)
+		}
+	}
+
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch data := data.(type) {
+	case map[string]interface{}:
+		for _, property := range sortedKeys(data) {
+			var propSchema *spec.Schema
+			if schema != nil {
+				if prop, ok := schema.Properties[property]; ok {
+					propSchema = &prop
+				}
+			}
+			if propSchema != nil && propSchema.XML != nil && propSchema.XML.Attribute {
+				continue
+			}
+			if err := encodeXMLProperty(encoder, property, data[property], propSchema); err != nil {
+				return err
+			}
+		}
+
+	case []interface{}:
+		itemSchema := xmlItemSchema(schema)
+		itemName := xmlName("item", itemSchema)
+		for _, item := range data {
+			if err := encodeXMLElement(encoder, itemName, item, itemSchema); err != nil {
+				return err
+			}
+		}
+
+	case nil:
+
+	default:
+		if err := encoder.EncodeToken(xml.CharData(xmlText(data))); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// encodeXMLProperty writes an object property. Array properties
+// repeat their item element directly inside the parent unless the
+// schema marks them as wrapped.
+func encodeXMLProperty(encoder *xml.Encoder, property string, data interface{}, schema *spec.Schema) error {
+	name := property
+	if schema != nil {
+		name = xmlName(property, schema)
+	}
+
+	items, isArray := data.([]interface{})
+	if !isArray || (schema != nil && schema.XML != nil && schema.XML.Wrapped) {
+		return encodeXMLElement(encoder, name, data, schema)
+	}
+
+	itemSchema := xmlItemSchema(schema)
+	itemName := name
+	if itemSchema != nil && itemSchema.XML != nil && itemSchema.XML.Name != "" {
+		itemName = itemSchema.XML.Name
+	}
+	for _, item := range items {
+		if err := encodeXMLElement(encoder, itemName, item, itemSchema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func xmlItemSchema(schema *spec.Schema) *spec.Schema {
+	if schema == nil || schema.Items == nil {
+		return nil
+	}
+	return schema.Items.Schema
+}
+
+// xmlQualifiedName applies the schema's xml prefix to the name
+func xmlQualifiedName(name string, schema *spec.Schema) xml.Name {
+	if schema != nil && schema.XML != nil && schema.XML.Prefix != "" {
+		return xml.Name{Local: schema.XML.Prefix + ":" + name}
+	}
+	return xml.Name{Local: name}
+}
+
+// xmlNamespaceAttrs declares the schema's xml namespace, bound
+// to it's prefix if it has one
+func xmlNamespaceAttrs(schema *spec.Schema) []xml.Attr {
+	if schema == nil || schema.XML == nil || schema.XML.Namespace == "" {
+		return nil
+	}
+	name := "xmlns"
+	if schema.XML.Prefix != "" {
+		name = "xmlns:" + schema.XML.Prefix
+	}
+	return []xml.Attr{{Name: xml.Name{Local: name}, Value: schema.XML.Namespace}}
+}
+
+func xmlText(data interface{}) string {
+	if data, ok := data.([]byte); ok {
+		return string(data)
+	}
+	return fmt.Sprint(data)
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
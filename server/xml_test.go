@@ -0,0 +1,61 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-openapi/spec"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeXML(t *testing.T) {
+	require := require.New(t)
+
+	schema := spec.Schema{
+		SchemaProps: spec.SchemaProps{
+			Type: spec.StringOrArray{"object"},
+			Properties: map[string]spec.Schema{
+				"id":   *spec.Int64Property().AsXMLAttribute(),
+				"name": *spec.StringProperty().WithXMLName("Name"),
+				"tags": *spec.ArrayProperty(spec.StringProperty().WithXMLName("tag")).AsWrappedXML(),
+			},
+		},
+	}
+	schema.WithXMLName("pet").WithXMLNamespace("http://example.com/schema").WithXMLPrefix("ex")
+
+	data := map[string]interface{}{
+		"id":   7,
+		"name": "rex",
+		"tags": []interface{}{"good", "dog"},
+	}
+
+	var buf bytes.Buffer
+	err := encodeXML(&buf, data, &schema)
+	require.NoError(err)
+
+	require.Equal(
+		`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+			`<ex:pet xmlns:ex="http://example.com/schema" id="7"><Name>rex</Name><tags><tag>good</tag><tag>dog</tag></tags></ex:pet>`,
+		buf.String(),
+	)
+}
+
+func TestEncodeXMLUnwrappedArray(t *testing.T) {
+	require := require.New(t)
+
+	schema := spec.Schema{
+		SchemaProps: spec.SchemaProps{
+			Type: spec.StringOrArray{"object"},
+			Properties: map[string]spec.Schema{
+				"tags": *spec.ArrayProperty(spec.StringProperty()),
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := encodeXML(&buf, map[string]interface{}{"tags": []interface{}{"a", "b"}}, &schema)
+	require.NoError(err)
+
+	require.Contains(buf.String(), `<response><tags>a</tags><tags>b</tags></response>`)
+}